@@ -0,0 +1,306 @@
+/*
+ delayrepay.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Delay Repay helper for journeys that have already been made.
+Journeys are logged in a CSV file with a header line, one per line:
+  train_uid,date,from,to
+eg. C20803,2019-10-25,OXF,PAD.  For each journey we fetch the service timetable
+again and read the arrival at the destination stop.  Once the train has arrived
+the live expected time is the actual arrival as far as transportAPI knows it.
+Operators pay out in bands of delay, 15, 30, 60 and 120 minutes under Delay
+Repay 15, but some only start at 30 minutes.  The first band can be set per
+operator in a CSV file given with --schemes:
+  operator,first_band
+eg. XC,30.  Cancelled trains are always listed so that the claim can be made on
+the delay to the next train.  --format=csv writes the journeys eligible for a
+claim in a form that can be copied into an operator's claim form, logging any
+journey it couldn't assess to stderr.
+
+Installation
+------------
+$ go run . repay journeys.csv --format=csv > claims.csv
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var DELAY_REPAY_BANDS = []int{15, 30, 60, 120}
+
+type LoggedJourney struct {
+	TrainUid string
+	Date     string
+	From     string
+	To       string
+}
+
+// RepayClaim is how late one logged journey arrived and what it can be claimed for
+type RepayClaim struct {
+	TrainUid           string `json:"train_uid"`
+	Date               string `json:"date"`
+	Operator           string `json:"operator"`
+	OperatorName       string `json:"operator_name"`
+	FromCode           string `json:"from_code"`
+	FromName           string `json:"from_name"`
+	ToCode             string `json:"to_code"`
+	ToName             string `json:"to_name"`
+	ScheduledDeparture string `json:"scheduled_departure_time"`
+	ActualDeparture    string `json:"actual_departure_time"`
+	ScheduledArrival   string `json:"scheduled_arrival_time"`
+	ActualArrival      string `json:"actual_arrival_time"`
+	DelayMins          int    `json:"delay_mins"`
+	Band               int    `json:"band_mins"`
+	Cancelled          bool   `json:"cancelled"`
+	Note               string `json:"note,omitempty"`
+}
+
+// Eligible reports whether the journey is worth claiming for
+func (claim RepayClaim) Eligible() bool {
+	return claim.Band > 0 || claim.Cancelled
+}
+
+func readCSV(csv_file string) [][]string {
+	f, err := os.Open(csv_file)
+	if err != nil {
+		log.Fatal("Cannot read ", csv_file, ": ", err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal("Cannot read ", csv_file, ": ", err)
+		}
+		records = append(records, record)
+	}
+	// Skip the header line
+	if len(records) > 0 {
+		records = records[1:]
+	}
+	return records
+}
+
+func readJourneys(journeys_file string) []LoggedJourney {
+	var journeys []LoggedJourney
+	for _, record := range readCSV(journeys_file) {
+		if len(record) < 4 {
+			log.Fatalf("%s: expected train_uid,date,from,to, got %q", journeys_file, strings.Join(record, ","))
+		}
+		journey := LoggedJourney{
+			TrainUid: strings.TrimSpace(record[0]),
+			Date:     strings.TrimSpace(record[1]),
+			From:     strings.ToUpper(strings.TrimSpace(record[2])),
+			To:       strings.ToUpper(strings.TrimSpace(record[3])),
+		}
+		if _, err := time.Parse(DATE_FORMAT, journey.Date); err != nil {
+			log.Fatalf("%s: invalid date %q for %s, expected YYYY-MM-DD", journeys_file, journey.Date, journey.TrainUid)
+		}
+		journeys = append(journeys, journey)
+	}
+	if len(journeys) == 0 {
+		log.Fatalf("No journeys in %s", journeys_file)
+	}
+	return journeys
+}
+
+// readSchemes reads the first Delay Repay band for operators that don't start at 15 minutes
+func readSchemes(schemes_file string) map[string]int {
+	schemes := make(map[string]int)
+	if len(schemes_file) == 0 {
+		return schemes
+	}
+	for _, record := range readCSV(schemes_file) {
+		if len(record) < 2 {
+			log.Fatalf("%s: expected operator,first_band, got %q", schemes_file, strings.Join(record, ","))
+		}
+		band, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			log.Fatalf("%s: bad first band for %s: %v", schemes_file, record[0], err)
+		}
+		schemes[strings.ToUpper(strings.TrimSpace(record[0]))] = band
+	}
+	return schemes
+}
+
+// repayBand returns the highest band a delay reaches, or 0 if it is too short to claim
+func repayBand(delay int, firstBand int) int {
+	band := 0
+	for _, b := range DELAY_REPAY_BANDS {
+		if b >= firstBand && delay >= b {
+			band = b
+		}
+	}
+	return band
+}
+
+// checkJourney works out the delay to one logged journey from its service timetable
+func checkJourney(journey LoggedJourney, schemes map[string]int, now time.Time) RepayClaim {
+	claim := RepayClaim{TrainUid: journey.TrainUid, Date: journey.Date, FromCode: journey.From, ToCode: journey.To}
+	service, err := fetchServiceTimetable(journey.TrainUid, journey.Date)
+	if err != nil {
+		claim.Note = err.Error()
+		return claim
+	}
+	claim.Operator = service.Operator
+	claim.OperatorName = service.OperatorName
+	origin, dest := routeStops(TrainTrip{Stops: service.Stops, FromCode: journey.From, ToCode: journey.To})
+	if origin == nil || dest == nil {
+		claim.Note = fmt.Sprintf("Train does not call at %s then %s", journey.From, journey.To)
+		return claim
+	}
+	claim.FromName = origin.StationName
+	claim.ToName = dest.StationName
+	claim.ScheduledDeparture = origin.AimedDeparture
+	claim.ActualDeparture = origin.ExpectedDeparture
+	claim.ScheduledArrival = dest.AimedArrival
+	claim.ActualArrival = dest.ExpectedArrival
+	if strings.EqualFold(origin.Status, "CANCELLED") || strings.EqualFold(dest.Status, "CANCELLED") {
+		claim.Cancelled = true
+		claim.Note = "Cancelled, claim for the delay to the train you caught instead"
+		return claim
+	}
+	if len(claim.ActualArrival) == 0 {
+		claim.Note = "No live arrival time recorded"
+		return claim
+	}
+	if !passed(dest.ExpectedArrivalDate, dest.ExpectedArrival, journey.Date, now) {
+		claim.Note = "Not arrived yet"
+		return claim
+	}
+	if delay, ok := lateness(claim.ScheduledArrival, claim.ActualArrival); ok && delay > 0 {
+		claim.DelayMins = delay
+	}
+	firstBand, ok := schemes[service.Operator]
+	if !ok {
+		firstBand = DELAY_REPAY_BANDS[0]
+	}
+	claim.Band = repayBand(claim.DelayMins, firstBand)
+	return claim
+}
+
+func getRepayClaims(journeys_file string, schemes_file string) []RepayClaim {
+	journeys := readJourneys(journeys_file)
+	schemes := readSchemes(schemes_file)
+	now := time.Now()
+	var claims []RepayClaim
+	for _, journey := range journeys {
+		claims = append(claims, checkJourney(journey, schemes, now))
+	}
+	return claims
+}
+
+func formatBand(claim RepayClaim) string {
+	if claim.Cancelled {
+		return "cancelled"
+	}
+	if claim.Band == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d+ mins", claim.Band)
+}
+
+func printClaimsCSV(claims []RepayClaim) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"train_uid", "date", "operator", "operator_name", "from_code", "from_name", "to_code", "to_name",
+		"scheduled_departure", "actual_departure", "scheduled_arrival", "actual_arrival", "delay_mins", "band_mins", "cancelled"})
+	for _, c := range claims {
+		if !c.Eligible() {
+			// A journey we couldn't assess may still be worth a claim so say why it's missing
+			if len(c.Note) > 0 {
+				slog.Warn("Journey left out of claims", "train_uid", c.TrainUid, "date", c.Date, "from", c.FromCode, "to", c.ToCode, "note", c.Note)
+			}
+			continue
+		}
+		w.Write([]string{c.TrainUid, c.Date, c.Operator, c.OperatorName, c.FromCode, c.FromName, c.ToCode, c.ToName,
+			c.ScheduledDeparture, c.ActualDeparture, c.ScheduledArrival, c.ActualArrival,
+			strconv.Itoa(c.DelayMins), strconv.Itoa(c.Band), strconv.FormatBool(c.Cancelled)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatal("Cannot write CSV: ", err)
+	}
+}
+
+func printClaimsTable(claims []RepayClaim) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRAIN\tDATE\tOPERATOR\tFROM\tTO\tSCHED ARR\tACTUAL ARR\tDELAY\tBAND\tNOTE\t")
+	for _, c := range claims {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t\n", c.TrainUid, c.Date, c.Operator, c.FromCode, c.ToCode,
+			c.ScheduledArrival, c.ActualArrival, c.DelayMins, formatBand(c), c.Note)
+	}
+	w.Flush()
+}
+
+// printOperatorClaims lists how many claims there are to make with each operator
+func printOperatorClaims(claims []RepayClaim) {
+	var operators []string
+	counts := make(map[string]int)
+	for _, c := range claims {
+		if !c.Eligible() {
+			continue
+		}
+		if _, ok := counts[c.OperatorName]; !ok {
+			operators = append(operators, c.OperatorName)
+		}
+		counts[c.OperatorName]++
+	}
+	if len(operators) == 0 {
+		fmt.Println("Nothing to claim")
+		return
+	}
+	for _, operator := range operators {
+		plural := "s"
+		if counts[operator] == 1 {
+			plural = ""
+		}
+		fmt.Println(fmt.Sprintf("%d claim%s to make with %s", counts[operator], plural, operator))
+	}
+}
+
+func formatClaims(claims []RepayClaim, format string) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(claims, "", "  ")
+		if err != nil {
+			log.Fatal("Cannot serialize JSON: ", err)
+		}
+		fmt.Println(string(data))
+	case "csv":
+		printClaimsCSV(claims)
+	default:
+		printHeader(fmt.Sprintf("==== Delay Repay for %d journeys ====", len(claims)))
+		printClaimsTable(claims)
+		fmt.Println()
+		printOperatorClaims(claims)
+	}
+}
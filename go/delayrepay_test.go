@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRepayBand(t *testing.T) {
+	tests := []struct {
+		delay     int
+		firstBand int
+		want      int
+	}{
+		{0, 15, 0},
+		{14, 15, 0},
+		{15, 15, 15},
+		{29, 15, 15},
+		{30, 15, 30},
+		{59, 15, 30},
+		{60, 15, 60},
+		{119, 15, 60},
+		{120, 15, 120},
+		{300, 15, 120},
+		// Delay Repay 30 operators don't pay for 15 minutes
+		{20, 30, 0},
+		{45, 30, 30},
+		{130, 30, 120},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d mins from %d", tt.delay, tt.firstBand), func(t *testing.T) {
+			if got := repayBand(tt.delay, tt.firstBand); got != tt.want {
+				t.Errorf("repayBand(%d, %d) = %d, want %d", tt.delay, tt.firstBand, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintClaimsCSVLogsSkippedJourneys(t *testing.T) {
+	var logged bytes.Buffer
+	// slog.SetDefault redirects the log package too and putting the default handler
+	// back doesn't undo that
+	previous, flags := slog.Default(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logged, nil)))
+	stdout := os.Stdout
+	devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devnull.Close()
+	os.Stdout = devnull
+	defer func() { os.Stdout = stdout }()
+
+	printClaimsCSV([]RepayClaim{
+		{TrainUid: "C1", Date: "2019-10-25", DelayMins: 40, Band: 30},
+		{TrainUid: "C2", Date: "2019-10-25", DelayMins: 5},
+		{TrainUid: "C3", Date: "2019-10-25", Note: "No live arrival time recorded"},
+		{TrainUid: "C4", Date: "2019-10-25", Cancelled: true, Note: "Cancelled, claim for the delay to the train you caught instead"},
+	})
+	tests := []struct {
+		uid    string
+		logged bool
+	}{
+		{"C1", false},
+		{"C2", false},
+		{"C3", true},
+		{"C4", false},
+	}
+	for _, tt := range tests {
+		if got := strings.Contains(logged.String(), "train_uid="+tt.uid); got != tt.logged {
+			t.Errorf("%s logged %v, want %v: %s", tt.uid, got, tt.logged, logged.String())
+		}
+	}
+}
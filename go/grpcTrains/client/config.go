@@ -0,0 +1,108 @@
+/*
+ config.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Connection settings for the Trains gRPC client.
+Anything not given on the command line falls back to an environment variable and
+then to a built in default, so the same settings can be shared with the server.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	address = "localhost:8001"
+	timeout = "10s"
+)
+
+type config struct {
+	Server     string
+	Timeout    time.Duration
+	TLS        bool
+	CA         string
+	Cert       string
+	Key        string
+	ServerName string
+	APIKey     string
+	Format     string
+}
+
+func envOr(value string, envvar string, fallback string) string {
+	if len(value) > 0 {
+		return value
+	}
+	if env, ok := os.LookupEnv(envvar); ok {
+		return env
+	}
+	return fallback
+}
+
+// resolve fills in anything not given on the command line and checks the result
+func (conf *config) resolve(timeoutOpt string) error {
+	conf.Server = envOr(conf.Server, "TRAINS_SERVER", address)
+	conf.CA = envOr(conf.CA, "TRAINS_TLS_CA", "")
+	conf.Cert = envOr(conf.Cert, "TRAINS_TLS_CERT", "")
+	conf.Key = envOr(conf.Key, "TRAINS_TLS_KEY", "")
+	conf.ServerName = envOr(conf.ServerName, "TRAINS_TLS_SERVER_NAME", "")
+	conf.APIKey = envOr(conf.APIKey, "TRAINS_API_KEY", "")
+	d, err := time.ParseDuration(envOr(timeoutOpt, "TRAINS_TIMEOUT", timeout))
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %v", err)
+	}
+	conf.Timeout = d
+	if (len(conf.Cert) > 0) != (len(conf.Key) > 0) {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	conf.TLS = conf.TLS || len(conf.CA) > 0 || len(conf.Cert) > 0
+	return nil
+}
+
+// dialOption returns insecure or TLS transport credentials depending on the config
+func (conf *config) dialOption() (grpc.DialOption, error) {
+	if !conf.TLS {
+		return grpc.WithInsecure(), nil
+	}
+	tlsConfig := &tls.Config{ServerName: conf.ServerName, MinVersion: tls.VersionTLS12}
+	if len(conf.CA) > 0 {
+		pem, err := ioutil.ReadFile(conf.CA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", conf.CA)
+		}
+	}
+	if len(conf.Cert) > 0 {
+		cert, err := tls.LoadX509KeyPair(conf.Cert, conf.Key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
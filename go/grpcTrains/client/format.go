@@ -0,0 +1,145 @@
+/*
+ format.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Output for the Trains gRPC client in the same text, table and JSON formats as
+trainsClient.go.  Text gives each departure followed by the stations it calls at
+between the two stations, table gives one line per departure and JSON is the
+TrainResponse as sent by the server with the field names from trains.proto.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	pb ".."
+
+	"github.com/golang/protobuf/jsonpb"
+)
+
+func printHeader(header string) {
+	headerBlock := strings.Repeat("=", len(header))
+	fmt.Println(headerBlock)
+	fmt.Println(header)
+	fmt.Println(headerBlock)
+}
+
+func formatHeader(r *pb.TrainResponse) string {
+	header := fmt.Sprintf("==== Trains from %s (%s) to %s", r.StationName, r.StationCode, r.DestName)
+	header += fmt.Sprintf("(%s) %s %s ====", r.DestCode, r.TimeOfDay, r.Date)
+	return header
+}
+
+func orDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
+
+func formatPlatform(platform int32) string {
+	if platform == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", platform)
+}
+
+// stopsOnRoute returns the stops from the origin station to the destination inclusive
+func stopsOnRoute(r *pb.TrainResponse, departure *pb.TrainResponse_TrainDeparture) []*pb.TrainResponse_TrainStop {
+	var route []*pb.TrainResponse_TrainStop
+	onRoute := false
+	for _, stop := range departure.Stops {
+		if stop.StationCode == r.StationCode {
+			onRoute = true
+		}
+		if onRoute {
+			route = append(route, stop)
+		}
+		if onRoute && stop.StationCode == r.DestCode {
+			break
+		}
+	}
+	return route
+}
+
+// destinationStop is the last stop on the route if it is the destination.  The
+// route runs on to the end of the train's stops if the destination isn't among them.
+func destinationStop(r *pb.TrainResponse, route []*pb.TrainResponse_TrainStop) pb.TrainResponse_TrainStop {
+	if len(route) > 0 && route[len(route)-1].StationCode == r.DestCode {
+		return *route[len(route)-1]
+	}
+	return pb.TrainResponse_TrainStop{}
+}
+
+func formatDeparture(r *pb.TrainResponse, departure *pb.TrainResponse_TrainDeparture) string {
+	route := stopsOnRoute(r, departure)
+	var source pb.TrainResponse_TrainStop
+	if len(route) > 0 {
+		source = *route[0]
+	}
+	dest := destinationStop(r, route)
+	text := fmt.Sprintf("%s %s -> %s", r.StationCode, orDash(departure.ExpectedDepartureTime), r.DestCode)
+	text += fmt.Sprintf(" %s => %s\n", orDash(dest.ExpectedArrival), departure.Status)
+	text += fmt.Sprintf("\tTrain %s (%s) from %s", departure.TrainUid, departure.Operator, departure.OriginName)
+	text += fmt.Sprintf(" arriving at %s on platform %s", r.StationName, formatPlatform(source.Platform))
+	text += fmt.Sprintf(" going to %s platform %s.", r.DestName, formatPlatform(dest.Platform))
+	text += fmt.Sprintf("  %d stops:", len(route))
+	var names []string
+	for _, stop := range route {
+		names = append(names, stop.StationName)
+	}
+	text += fmt.Sprintf("\n\t%s", strings.Join(names, ", "))
+	return text
+}
+
+func printTrainsTable(r *pb.TrainResponse) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPART\tARRIVE\tPLAT\tSTOPS\tSTATUS\tTRAIN\tOPERATOR\tFROM")
+	for _, departure := range r.Departures {
+		route := stopsOnRoute(r, departure)
+		arrive := destinationStop(r, route).ExpectedArrival
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", orDash(departure.ExpectedDepartureTime), orDash(arrive),
+			formatPlatform(departure.Platform), len(route), departure.Status, departure.TrainUid,
+			departure.Operator, departure.OriginName)
+	}
+	w.Flush()
+}
+
+func formatTrains(r *pb.TrainResponse, format string) {
+	switch format {
+	case "json":
+		marshaler := jsonpb.Marshaler{OrigName: true, EmitDefaults: true, Indent: "  "}
+		data, err := marshaler.MarshalToString(r)
+		if err != nil {
+			log.Fatal("Cannot serialize JSON: ", err)
+		}
+		fmt.Print(data)
+	case "table":
+		printHeader(formatHeader(r))
+		printTrainsTable(r)
+	default:
+		printHeader(formatHeader(r))
+		for _, departure := range r.Departures {
+			fmt.Println(formatDeparture(r, departure))
+		}
+	}
+	fmt.Println()
+}
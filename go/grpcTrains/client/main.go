@@ -15,7 +15,8 @@ Installation
 $ export GOPATH=<full path to local directory>
 $ go get -v github.com/docopt/docopt-go
 
-$ go run -ldflags="-s -w" main.go
+$ go run -ldflags="-s -w" ./client <from> <to> [--server=<addr>] [--timeout=<duration>] [--format=<fmt>]
+$ go run ./client TWY PAD --tls-ca=certs/ca.pem --tls-cert=certs/client.pem --tls-key=certs/client-key.pem
 
 Version
 -------
@@ -26,62 +27,120 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"time"
+	"os"
+	"strings"
 
 	pb ".."
+
+	docopt "github.com/docopt/docopt-go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-const (
-	address = "localhost:8001"
-)
+const PROGRAM = "client"
+const VERSION = "0.1"
+const DATE = "22.07.19"
+const AUTHOR = "Mal Minhas"
 
-func main() {
+func getTrains(conf *config, from string, to string) (*pb.TrainResponse, error) {
+	creds, err := conf.dialOption()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration: %v", err)
+	}
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	conn, err := grpc.Dial(conf.Server, creds)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		return nil, fmt.Errorf("did not connect: %v", err)
 	}
 	defer conn.Close()
 	c := pb.NewTrainServiceClient(conn)
 
-	// Contact the server and print out its response.
-	//from := defaultFrom
-	//if len(os.Args) > 1 {
-	//	from = os.Args[1]
-	//}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	// Contact the server and return its response.
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Timeout)
 	defer cancel()
-	r, err := c.GetTrains(ctx, &pb.TrainRequest{From: "TWY", To: "PAD"})
+	if len(conf.APIKey) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", conf.APIKey)
+	}
+	return c.GetTrains(ctx, &pb.TrainRequest{From: from, To: to})
+}
+
+// ---------- main  ----------
+func procOpts(opts *docopt.Opts) {
+	var args struct {
+		StationCode     string `docopt:"<from>"`
+		DestinationCode string `docopt:"<to>"`
+		Server          string `docopt:"--server"`
+		Timeout         string `docopt:"--timeout"`
+		Format          string `docopt:"--format"`
+		TLS             bool   `docopt:"--tls"`
+		CA              string `docopt:"--tls-ca"`
+		Cert            string `docopt:"--tls-cert"`
+		Key             string `docopt:"--tls-key"`
+		ServerName      string `docopt:"--tls-server-name"`
+		APIKey          string `docopt:"--api-key"`
+	}
+	opts.Bind(&args)
+
+	conf := &config{
+		Server:     args.Server,
+		TLS:        args.TLS,
+		CA:         args.CA,
+		Cert:       args.Cert,
+		Key:        args.Key,
+		ServerName: args.ServerName,
+		APIKey:     args.APIKey,
+		Format:     args.Format,
+	}
+	if err := conf.resolve(args.Timeout); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	stationCode := strings.ToUpper(args.StationCode)
+	destCode := strings.ToUpper(args.DestinationCode)
+	if len(stationCode) != 3 || len(destCode) != 3 {
+		log.Fatal("from and to must be three letter CRS codes")
+	}
+	r, err := getTrains(conf, stationCode, destCode)
 	if err != nil {
-		log.Fatalf("could not greet: %v", err)
+		log.Fatalf("could not get trains: %v", err)
 	}
-	log.Printf("From: %s (%s), To: %s", r.StationCode, r.StationName, r.DestCode)
+	formatTrains(r, conf.Format)
 }
 
-/*
-usage := fmt.Sprintf(`
-%s
----------
-Usage:
-%s <from> <to>
-%s -h | --help
-%s -V | --version
-
-Options:
--h --help               Show this screen.
--V --version            Show version.
-
-Examples
-1. trains from RDG to PAD:
-%s RDG PAD
-`, PROGRAM, PROGRAM, PROGRAM, PROGRAM, PROGRAM)
-APP_ID = readCred(".transportAppId")
-APP_KEY = readCred(".transportAppKey")
-
-// Process error handling
-version := fmt.Sprintf("%s %s %s", VERSION, DATE, AUTHOR)
-opts, _ := docopt.ParseArgs(usage, os.Args[1:], version)
-procOpts(&opts)
-*/
+func main() {
+	usage := fmt.Sprintf(`
+    %[1]s
+    ---------
+    Usage:
+    %[1]s <from> <to> [options]
+    %[1]s -h | --help
+    %[1]s -V | --version
+
+    Options:
+    -h --help                   Show this screen.
+    -V --version                Show version.
+    --server=<addr>             Server address, else TRAINS_SERVER or localhost:8001.
+    --timeout=<duration>        Request timeout eg. 30s, else TRAINS_TIMEOUT or 10s.
+    --format=<fmt>              Output format: text, table or json [default: text].
+    --tls                       Connect using TLS with the system root CAs.
+    --tls-ca=<file>             CA for the server certificate, else TRAINS_TLS_CA.
+    --tls-cert=<file>           Client certificate for mTLS, else TRAINS_TLS_CERT.
+    --tls-key=<file>            Client private key for mTLS, else TRAINS_TLS_KEY.
+    --tls-server-name=<name>    Name expected on the server certificate.
+    --api-key=<key>             API key for the server, else TRAINS_API_KEY.
+
+    Examples
+    1. trains from RDG to PAD:
+    %[1]s RDG PAD
+    2. trains from OXF to PAD as a table from a remote server:
+    %[1]s OXF PAD --server=trains.example.com:8001 --tls --format=table
+    3. trains from TWY to PAD as JSON with a longer timeout:
+    %[1]s TWY PAD --timeout=30s --format=json
+`, PROGRAM)
+
+	// Process error handling
+	version := fmt.Sprintf("%s %s %s", VERSION, DATE, AUTHOR)
+	opts, _ := docopt.ParseArgs(usage, os.Args[1:], version)
+	procOpts(&opts)
+}
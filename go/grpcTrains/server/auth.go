@@ -0,0 +1,262 @@
+/*
+ auth.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+API key authentication, rate limits and daily quotas for the Trains server.
+Every board costs us a handful of transportAPI calls, so once the server is
+exposed callers must identify themselves with an API key, sent either as x-api-key
+metadata or as an authorization: Bearer token.  Keys are read from a CSV file,
+one client per line:
+  name,key,per_minute,per_day
+where the limits are optional and fall back to -rate-limit and -daily-quota.
+TRAINS_API_KEYS can hold name:key pairs separated by commas instead, eg. in a
+container.  Each key has its own token bucket refilled at per_minute calls a
+minute and a count of calls that resets at midnight UK time.  Counts are only kept
+in memory so restarting the server resets them.  With no keys configured the
+server stays open as before.  Health checks and reflection are always allowed.
+The station registry calls are answered from station_codes.csv without calling
+transportAPI, so they still need a valid key but aren't counted against its limits.
+
+Installation
+------------
+$ printf "name,key,per_minute,per_day\nmal,$(openssl rand -hex 16),60,2000\n" > .trainsApiKeys
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	API_KEYS_FILE = ".trainsApiKeys"
+	apiKeyHeader  = "x-api-key"
+	rateLimit     = 60
+	dailyQuota    = 1000
+)
+
+type apiClient struct {
+	name      string
+	perMinute int
+	perDay    int
+
+	mu     sync.Mutex
+	tokens float64
+	filled time.Time
+	day    string
+	used   int
+}
+
+// take uses up one call, returning a ResourceExhausted error if the client is
+// over its rate limit or daily quota
+func (c *apiClient) take(now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if day := now.In(london).Format(DATE_FORMAT); day != c.day {
+		c.day = day
+		c.used = 0
+	}
+	if c.used >= c.perDay {
+		return status.Errorf(codes.ResourceExhausted, "daily quota of %d requests used up for %s, it resets at midnight UK time", c.perDay, c.name)
+	}
+	c.tokens += now.Sub(c.filled).Minutes() * float64(c.perMinute)
+	if c.tokens > float64(c.perMinute) {
+		c.tokens = float64(c.perMinute)
+	}
+	c.filled = now
+	if c.tokens < 1 {
+		wait := time.Duration((1 - c.tokens) / float64(c.perMinute) * float64(time.Minute))
+		return status.Errorf(codes.ResourceExhausted, "rate limit of %d requests a minute exceeded for %s, retry in %s", c.perMinute, c.name, wait.Round(time.Second))
+	}
+	c.tokens--
+	c.used++
+	return nil
+}
+
+// authenticator holds the clients allowed to call the server, by a hash of their key
+type authenticator struct {
+	clients map[[sha256.Size]byte]*apiClient
+}
+
+func (a *authenticator) add(name string, key string, perMinute int, perDay int) error {
+	if len(name) == 0 || len(key) == 0 {
+		return fmt.Errorf("API keys need a name and a key")
+	}
+	if perMinute <= 0 || perDay <= 0 {
+		return fmt.Errorf("limits for %s must be positive", name)
+	}
+	hash := sha256.Sum256([]byte(key))
+	if _, ok := a.clients[hash]; ok {
+		return fmt.Errorf("duplicate API key for %s", name)
+	}
+	a.clients[hash] = &apiClient{name: name, perMinute: perMinute, perDay: perDay, tokens: float64(perMinute), filled: time.Now()}
+	return nil
+}
+
+func parseLimit(field string, value int) (int, error) {
+	field = strings.TrimSpace(field)
+	if len(field) == 0 {
+		return value, nil
+	}
+	return strconv.Atoi(field)
+}
+
+// loadAPIKeys reads the keys from the TRAINS_API_KEYS environment variable, or
+// failing that from keys_file.  A missing file leaves authentication off.
+func loadAPIKeys(keys_file string, perMinute int, perDay int) (*authenticator, error) {
+	a := &authenticator{clients: make(map[[sha256.Size]byte]*apiClient)}
+	if env := os.Getenv("TRAINS_API_KEYS"); len(env) > 0 {
+		for _, pair := range strings.Split(env, ",") {
+			name, key, _ := strings.Cut(strings.TrimSpace(pair), ":")
+			if err := a.add(name, key, perMinute, perDay); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	if len(keys_file) == 0 || !ExistsFile(keys_file) {
+		return a, nil
+	}
+	f, err := os.Open(keys_file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	// Skip the header line
+	if _, err := r.Read(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("%s: expected name,key[,per_minute,per_day], got %q", keys_file, strings.Join(record, ","))
+		}
+		limits := []int{perMinute, perDay}
+		for i := range limits {
+			if len(record) > i+2 {
+				if limits[i], err = parseLimit(record[i+2], limits[i]); err != nil {
+					return nil, fmt.Errorf("%s: bad limit for %s: %v", keys_file, record[0], err)
+				}
+			}
+		}
+		if err := a.add(strings.TrimSpace(record[0]), strings.TrimSpace(record[1]), limits[0], limits[1]); err != nil {
+			return nil, fmt.Errorf("%s: %v", keys_file, err)
+		}
+	}
+	return a, nil
+}
+
+// Enabled is false when no keys are configured and anyone may call the server
+func (a *authenticator) Enabled() bool {
+	return len(a.clients) > 0
+}
+
+// identify finds the client for key, or nil if authentication is off
+func (a *authenticator) identify(key string) (*apiClient, error) {
+	if !a.Enabled() {
+		return nil, nil
+	}
+	if len(key) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing API key, send it as x-api-key or authorization: Bearer <key>")
+	}
+	client, ok := a.clients[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown API key")
+	}
+	return client, nil
+}
+
+// Check finds the client for key and charges it for one call
+func (a *authenticator) Check(key string) error {
+	client, err := a.identify(key)
+	if client == nil {
+		return err
+	}
+	return client.take(time.Now())
+}
+
+// bearerKey takes the key from an x-api-key value or an authorization: Bearer value
+func bearerKey(apiKey string, authorization string) string {
+	if len(apiKey) > 0 {
+		return strings.TrimSpace(apiKey)
+	}
+	if scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// uncharged are the methods that don't call transportAPI
+var uncharged = map[string]bool{
+	"/trains.TrainService/ListStations":   true,
+	"/trains.TrainService/GetStation":     true,
+	"/trains.TrainService/SearchStations": true,
+}
+
+func (a *authenticator) checkContext(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, "/grpc.health.v1.Health/") || strings.HasPrefix(method, "/grpc.reflection.") {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := bearerKey(firstValue(md, apiKeyHeader), firstValue(md, "authorization"))
+	if uncharged[method] {
+		_, err := a.identify(key)
+		return err
+	}
+	return a.Check(key)
+}
+
+func (a *authenticator) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.checkContext(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor charges a stream as one call when it starts
+func (a *authenticator) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.checkContext(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIClientRateLimit(t *testing.T) {
+	start := time.Date(2019, 10, 26, 10, 0, 0, 0, london)
+	c := &apiClient{name: "test", perMinute: 2, perDay: 100, tokens: 2, filled: start}
+	tests := []struct {
+		after time.Duration
+		want  codes.Code
+	}{
+		{0, codes.OK},
+		{0, codes.OK},
+		{0, codes.ResourceExhausted},
+		{20 * time.Second, codes.ResourceExhausted},
+		// Refilled at 2 a minute so one token after 30 seconds
+		{30 * time.Second, codes.OK},
+		{30 * time.Second, codes.ResourceExhausted},
+		// The bucket only holds a minute's worth
+		{10 * time.Minute, codes.OK},
+		{10 * time.Minute, codes.OK},
+		{10 * time.Minute, codes.ResourceExhausted},
+	}
+	for i, tt := range tests {
+		if got := status.Code(c.take(start.Add(tt.after))); got != tt.want {
+			t.Errorf("call %d after %s = %v, want %v", i, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestAPIClientDailyQuota(t *testing.T) {
+	// 23:59 UK time is already the next day in UTC during summer time
+	lastMinute := time.Date(2019, 8, 26, 23, 59, 0, 0, london)
+	c := &apiClient{name: "test", perMinute: 100, perDay: 2, tokens: 100, filled: lastMinute}
+	tests := []struct {
+		at   time.Time
+		want codes.Code
+	}{
+		{lastMinute, codes.OK},
+		{lastMinute, codes.OK},
+		{lastMinute.Add(30 * time.Second), codes.ResourceExhausted},
+		{lastMinute.Add(90 * time.Second), codes.OK},
+		{lastMinute.Add(90 * time.Second), codes.OK},
+		{lastMinute.Add(2 * time.Minute), codes.ResourceExhausted},
+	}
+	for i, tt := range tests {
+		if got := status.Code(c.take(tt.at)); got != tt.want {
+			t.Errorf("call %d at %s = %v, want %v", i, tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestCheckContext(t *testing.T) {
+	a := &authenticator{clients: make(map[[32]byte]*apiClient)}
+	if err := a.add("test", "secret", 60, 1000); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method  string
+		key     string
+		want    codes.Code
+		charged bool
+	}{
+		{"/trains.TrainService/GetTrains", "secret", codes.OK, true},
+		{"/trains.TrainService/GetTrains", "", codes.Unauthenticated, false},
+		{"/trains.TrainService/GetTrains", "wrong", codes.Unauthenticated, false},
+		{"/trains.TrainService/WatchDepartures", "secret", codes.OK, true},
+		{"/trains.TrainService/ListStations", "secret", codes.OK, false},
+		{"/trains.TrainService/SearchStations", "secret", codes.OK, false},
+		{"/trains.TrainService/GetStation", "", codes.Unauthenticated, false},
+		{"/grpc.health.v1.Health/Check", "", codes.OK, false},
+		{"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", "", codes.OK, false},
+		{"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "", codes.OK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.key, func(t *testing.T) {
+			client, _ := a.identify("secret")
+			used := client.used
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tt.key))
+			if got := status.Code(a.checkContext(ctx, tt.method)); got != tt.want {
+				t.Errorf("checkContext() = %v, want %v", got, tt.want)
+			}
+			if charged := client.used > used; charged != tt.charged {
+				t.Errorf("charged %v, want %v", charged, tt.charged)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ board.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+HTML departure board served by the Go server at /board?from=TWY&to=PAD.
+This replaces the expressTrainsServer.js page which was the text board with
+newlines turned into <br>.  The page is rendered with html/template from the
+version 2 messages so it has proper times, platforms like "4A" and statuses.
+It refreshes itself every minute, fits on a phone as well as the office screens
+and shows delayed and cancelled trains in their own colours.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	pb ".."
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc/status"
+)
+
+const BOARD_REFRESH_SECS = 60
+
+// boardMethod labels the board's calls in logs and metrics alongside the gRPC methods
+const boardMethod = "/board"
+
+type boardRow struct {
+	Due         string
+	Expected    string
+	Arrives     string
+	Platform    string
+	Destination string
+	Operator    string
+	TrainUid    string
+	Status      string
+	Class       string
+}
+
+type boardPage struct {
+	Title   string
+	Refresh int
+	Updated string
+	Error   string
+	Rows    []boardRow
+}
+
+var boardTemplate = template.Must(template.New("board").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; padding: 1em; background: #111; color: #fc0; font-family: "Helvetica Neue", Arial, sans-serif; }
+h1 { font-size: 1.4em; margin: 0 0 0.2em 0; }
+p.updated { color: #999; margin: 0 0 1em 0; }
+p.error { color: #f66; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: 0.4em 0.5em; border-bottom: 1px solid #333; }
+th { color: #999; font-weight: normal; }
+tr.late td.expected, tr.late td.status { color: #f90; }
+tr.cancelled td { color: #f66; text-decoration: line-through; }
+tr.cancelled td.status { text-decoration: none; }
+@media (max-width: 600px) {
+  body { padding: 0.5em; font-size: 0.9em; }
+  .wide { display: none; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Updated}}<p class="updated">Updated {{.Updated}}</p>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{else if not .Rows}}<p>No direct trains at the moment.</p>{{else}}
+<table>
+<tr><th>Due</th><th>Expected</th><th>Plat</th><th>Destination</th><th>Arrives</th><th class="wide">Operator</th><th class="wide">Train</th><th>Status</th></tr>
+{{range .Rows}}<tr class="{{.Class}}"><td>{{.Due}}</td><td class="expected">{{.Expected}}</td><td>{{.Platform}}</td><td>{{.Destination}}</td><td>{{.Arrives}}</td><td class="wide">{{.Operator}}</td><td class="wide">{{.TrainUid}}</td><td class="status">{{.Status}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+func clockTime(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.In(london).Format("15:04")
+}
+
+func statusText(status pb.TrainStatus) string {
+	if status == pb.TrainStatus_TRAIN_STATUS_UNKNOWN {
+		return ""
+	}
+	name := strings.TrimPrefix(status.String(), "TRAIN_STATUS_")
+	return strings.Replace(name, "_", " ", -1)
+}
+
+func newBoardRow(departure *pb.TrainDepartureV2, dest_code string) boardRow {
+	row := boardRow{
+		Due:         clockTime(departure.AimedDeparture),
+		Expected:    clockTime(departure.ExpectedDeparture),
+		Platform:    departure.Platform,
+		Destination: departure.DestinationName,
+		Operator:    departure.OperatorName,
+		TrainUid:    departure.TrainUid,
+		Status:      statusText(departure.Status),
+	}
+	for _, stop := range departure.Stops {
+		if stop.StationCode == dest_code {
+			row.Arrives = clockTime(stop.ExpectedArrival)
+			if len(row.Arrives) == 0 {
+				row.Arrives = clockTime(stop.AimedArrival)
+			}
+			break
+		}
+	}
+	switch {
+	case departure.Status == pb.TrainStatus_TRAIN_STATUS_CANCELLED:
+		row.Class = "cancelled"
+	case departure.Status == pb.TrainStatus_TRAIN_STATUS_LATE:
+		row.Class = "late"
+	case departure.Delay != nil && departure.Delay.Seconds > 0:
+		row.Class = "late"
+	}
+	if row.Expected == row.Due && row.Class == "" {
+		row.Expected = "On time"
+	}
+	return row
+}
+
+func newBoardPage(response *pb.TrainResponseV2) boardPage {
+	page := boardPage{
+		Title:   response.StationName + " to " + response.DestName,
+		Refresh: BOARD_REFRESH_SECS,
+		Updated: clockTime(response.RequestTime),
+	}
+	for _, departure := range response.Departures {
+		page.Rows = append(page.Rows, newBoardRow(departure, response.DestCode))
+	}
+	return page
+}
+
+// newBoardHandler serves the HTML board for the from and to query parameters.
+// Browsers can't add headers to a page that refreshes itself so the API key may
+// also be given as the key query parameter.
+func newBoardHandler(s *server, auth *authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &pb.TrainRequest{
+			From: strings.ToUpper(r.URL.Query().Get("from")),
+			To:   strings.ToUpper(r.URL.Query().Get("to")),
+		}
+		page := boardPage{Title: "Trains", Refresh: BOARD_REFRESH_SECS}
+		code := http.StatusOK
+		start := time.Now()
+		// The board calls GetTrainsV2 directly, missing the gRPC interceptors, so it
+		// needs its own request ID and is logged and counted here
+		ctx := withRequestID(r.Context(), r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID(ctx))
+		key := bearerKey(r.Header.Get(apiKeyHeader), r.Header.Get("Authorization"))
+		if len(key) == 0 {
+			key = r.URL.Query().Get("key")
+		}
+		var response *pb.TrainResponseV2
+		err := auth.Check(key)
+		if err == nil {
+			response, err = s.GetTrainsV2(ctx, in)
+		}
+		observeRPC(boardMethod, start, err)
+		logRPC(ctx, boardMethod, start, err)
+		if err != nil {
+			code = runtime.HTTPStatusFromCode(status.Code(err))
+			page.Error = status.Convert(err).Message()
+		} else {
+			page = newBoardPage(response)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(code)
+		_, span := tracer.Start(ctx, "render board")
+		err = boardTemplate.Execute(w, page)
+		endSpan(span, err)
+		if err != nil {
+			slog.ErrorContext(ctx, "Cannot render board", "error", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func metricsText(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	data, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBoardHandlerIsLoggedAndCounted(t *testing.T) {
+	var logged bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logged, nil)))
+	auth := &authenticator{clients: make(map[[32]byte]*apiClient)}
+	if err := auth.add("test", "secret", 60, 1000); err != nil {
+		t.Fatal(err)
+	}
+	handler := newBoardHandler(&server{}, auth)
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/board?from=TWY&to=PAD", http.StatusUnauthorized},
+		{"/board?from=TWY&to=PAD&key=wrong", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.url, rec.Code, tt.code)
+		}
+	}
+	if want := `trains_grpc_requests_total{code="Unauthenticated",method="/board"} 2`; !strings.Contains(metricsText(t), want) {
+		t.Errorf("metrics missing %s", want)
+	}
+	if n := strings.Count(logged.String(), "method=/board"); n != 2 {
+		t.Errorf("board logged %d times, want 2: %s", n, logged.String())
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ breaker.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Circuit breaker in front of transportAPI.
+After a run of consecutive failures the circuit opens and upstream calls fail
+straight away rather than piling up behind a service that is down.  Once the
+cooldown has passed a single trial call is let through: if it works the circuit
+closes again, otherwise it stays open for another cooldown.  A call whose caller
+gives up counts as neither and lets another call make the trial.  Only network
+errors and 5xx responses count as failures, so asking for a train that doesn't
+exist won't open the circuit.  Changes of state are reported so that the health service
+can tell load balancers to stop sending us traffic.  Once the cooldown has passed
+the circuit is reported closed again, so that a server taken out of service by
+its readiness probe gets the traffic to make the trial call.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	breakerFailures = 5
+	breakerCooldown = 30 * time.Second
+)
+
+var errCircuitOpen = errors.New("transportAPI circuit is open after repeated failures")
+
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	trial     bool
+	openedAt  time.Time
+	timer     *time.Timer
+	reported  bool
+	onChange  func(open bool)
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, onChange func(open bool)) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, onChange: onChange}
+}
+
+// Allow returns errCircuitOpen unless a call may go ahead
+func (b *circuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return errCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// Record reports the outcome of a call that Allow let through
+func (b *circuitBreaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if failed {
+		b.failures++
+		if b.failures >= b.threshold || b.open {
+			if !b.open {
+				slog.Warn("transportAPI circuit opened", "failures", b.failures)
+			}
+			b.open = true
+			b.openedAt = time.Now()
+			b.report(true)
+			if b.timer != nil {
+				b.timer.Stop()
+			}
+			b.timer = time.AfterFunc(b.cooldown, b.halfOpen)
+		}
+		return
+	}
+	if b.open {
+		slog.Info("transportAPI circuit closed")
+	}
+	b.failures = 0
+	b.open = false
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.report(false)
+}
+
+// Release gives up a call that Allow let through without knowing how it went,
+// eg. because the caller went away, so a later call can be the trial instead
+func (b *circuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// halfOpen runs once the cooldown has passed.  The circuit stays open until a
+// trial call works but we report it as closed so that a load balancer that took
+// us out of service sends the traffic that can make the trial call.
+func (b *circuitBreaker) halfOpen() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open && time.Since(b.openedAt) >= b.cooldown {
+		slog.Info("transportAPI circuit half open, allowing a trial call")
+		b.report(false)
+	}
+}
+
+// report must be called with b.mu held.  It passes on changes to the state we report.
+func (b *circuitBreaker) report(open bool) {
+	if open == b.reported {
+		return
+	}
+	b.reported = open
+	if b.onChange != nil {
+		b.onChange(open)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	var changes []bool
+	b := newCircuitBreaker(3, time.Hour, func(open bool) { changes = append(changes, open) })
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("call %d: Allow() = %v, want nil", i, err)
+		}
+		b.Record(true)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() before threshold = %v, want nil", err)
+	}
+	b.Record(true)
+	if err := b.Allow(); err != errCircuitOpen {
+		t.Fatalf("Allow() after threshold = %v, want errCircuitOpen", err)
+	}
+	if len(changes) != 1 || !changes[0] {
+		t.Errorf("changes = %v, want [true]", changes)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b := newCircuitBreaker(2, time.Hour, nil)
+	b.Record(true)
+	b.Record(false)
+	b.Record(true)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v, want nil as failures weren't consecutive", err)
+	}
+}
+
+func TestCircuitBreakerTrial(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcome  func(b *circuitBreaker)
+		wantOpen bool
+	}{
+		{"trial works", func(b *circuitBreaker) { b.Record(false) }, false},
+		{"trial fails", func(b *circuitBreaker) { b.Record(true) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(1, 10*time.Millisecond, nil)
+			b.Record(true)
+			time.Sleep(20 * time.Millisecond)
+			if err := b.Allow(); err != nil {
+				t.Fatalf("trial Allow() = %v, want nil", err)
+			}
+			if err := b.Allow(); err != errCircuitOpen {
+				t.Fatalf("second Allow() during trial = %v, want errCircuitOpen", err)
+			}
+			tt.outcome(b)
+			if b.open != tt.wantOpen {
+				t.Errorf("open = %v, want %v", b.open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerReleaseKeepsState(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond, nil)
+	b.Record(true)
+	time.Sleep(20 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial Allow() = %v, want nil", err)
+	}
+	b.Release()
+	if !b.open || b.failures != 1 {
+		t.Fatalf("after Release open = %v failures = %d, want true and 1", b.open, b.failures)
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() after Release = %v, want a new trial", err)
+	}
+
+	closed := newCircuitBreaker(3, time.Hour, nil)
+	closed.Record(true)
+	closed.Record(true)
+	closed.Release()
+	if closed.failures != 2 {
+		t.Errorf("Release cleared failures, got %d want 2", closed.failures)
+	}
+}
+
+func TestCircuitBreakerReportsHalfOpen(t *testing.T) {
+	changes := make(chan bool, 4)
+	b := newCircuitBreaker(1, 10*time.Millisecond, func(open bool) { changes <- open })
+	b.Record(true)
+	for _, want := range []bool{true, false} {
+		select {
+		case open := <-changes:
+			if open != want {
+				t.Fatalf("reported open = %v, want %v", open, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no report of open = %v", want)
+		}
+	}
+	// A failed trial reports the circuit open again
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial Allow() = %v, want nil", err)
+	}
+	b.Record(true)
+	if open := <-changes; !open {
+		t.Errorf("reported open = false after a failed trial")
+	}
+}
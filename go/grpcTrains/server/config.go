@@ -0,0 +1,245 @@
+/*
+ config.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Configuration for the Trains gRPC server.
+Every setting can be given as a flag and defaults to an environment variable so
+that the server can be configured either way, eg. in a container.  Flags win over
+environment variables which win over the built in defaults.
+With -tls-cert and -tls-key the gRPC port and the REST gateway both use TLS.
+Adding -tls-client-ca requires gRPC clients to present a certificate signed by
+that CA, and REST clients too with -http-client-auth.
+The gateway calls the gRPC server itself so it verifies the server against -tls-ca
+and presents the server certificate as its own client certificate.
+
+Installation
+------------
+See ServerSideScripts.md for generating certificates locally with openssl.
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+type config struct {
+	ListenAddr      string
+	HTTPAddr        string
+	TLSCert         string
+	TLSKey          string
+	TLSClientCA     string
+	TLSCA           string
+	UpstreamURL     string
+	UpstreamTimeout time.Duration
+	PollInterval    time.Duration
+	HTTPReadTimeout time.Duration
+	ShutdownTimeout time.Duration
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	AppIdFile       string
+	AppKeyFile      string
+	StationsCSV     string
+	OpenAPIFile     string
+	LogLevel        string
+	LogJSON         bool
+	TraceExporter   string
+	APIKeysFile     string
+	RateLimit       int
+	DailyQuota      int
+	GTFSRTStations  string
+	HTTPClientAuth  bool
+}
+
+func envOr(envvar string, value string) string {
+	if env, ok := os.LookupEnv(envvar); ok {
+		return env
+	}
+	return value
+}
+
+// envParser reads flag defaults from the environment.  It keeps the first value it
+// couldn't parse so that a bad environment variable fails like a bad flag.
+type envParser struct {
+	err error
+}
+
+func (e *envParser) fail(envvar string, value string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid value %q for %s: %v", value, envvar, err)
+	}
+}
+
+func (e *envParser) Duration(envvar string, value time.Duration) time.Duration {
+	if env, ok := os.LookupEnv(envvar); ok {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			e.fail(envvar, env, err)
+			return value
+		}
+		return d
+	}
+	return value
+}
+
+func (e *envParser) Int(envvar string, value int) int {
+	if env, ok := os.LookupEnv(envvar); ok {
+		n, err := strconv.Atoi(env)
+		if err != nil {
+			e.fail(envvar, env, err)
+			return value
+		}
+		return n
+	}
+	return value
+}
+
+func (e *envParser) Bool(envvar string, value bool) bool {
+	if env, ok := os.LookupEnv(envvar); ok {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			e.fail(envvar, env, err)
+			return value
+		}
+		return b
+	}
+	return value
+}
+
+func parseConfig(args []string) (*config, error) {
+	conf := &config{}
+	env := &envParser{}
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&conf.ListenAddr, "listen", envOr("TRAINS_LISTEN_ADDR", port), "gRPC listen address [TRAINS_LISTEN_ADDR]")
+	fs.StringVar(&conf.HTTPAddr, "http", envOr("TRAINS_HTTP_ADDR", httpPort), "REST gateway listen address, empty to disable it [TRAINS_HTTP_ADDR]")
+	fs.StringVar(&conf.TLSCert, "tls-cert", envOr("TRAINS_TLS_CERT", ""), "server certificate file [TRAINS_TLS_CERT]")
+	fs.StringVar(&conf.TLSKey, "tls-key", envOr("TRAINS_TLS_KEY", ""), "server private key file [TRAINS_TLS_KEY]")
+	fs.StringVar(&conf.TLSClientCA, "tls-client-ca", envOr("TRAINS_TLS_CLIENT_CA", ""), "CA for verifying client certificates, enables mTLS [TRAINS_TLS_CLIENT_CA]")
+	fs.StringVar(&conf.TLSCA, "tls-ca", envOr("TRAINS_TLS_CA", ""), "CA the gateway uses to verify the server, system roots if empty [TRAINS_TLS_CA]")
+	fs.StringVar(&conf.UpstreamURL, "upstream", envOr("TRAINS_UPSTREAM_URL", UPSTREAM_URL), "transportAPI base URL [TRAINS_UPSTREAM_URL]")
+	fs.DurationVar(&conf.UpstreamTimeout, "upstream-timeout", env.Duration("TRAINS_UPSTREAM_TIMEOUT", UPSTREAM_TIMEOUT), "timeout for each transportAPI request [TRAINS_UPSTREAM_TIMEOUT]")
+	fs.DurationVar(&conf.PollInterval, "poll-interval", env.Duration("TRAINS_POLL_INTERVAL", pollInterval), "interval between polls for watched routes [TRAINS_POLL_INTERVAL]")
+	fs.DurationVar(&conf.HTTPReadTimeout, "http-read-timeout", env.Duration("TRAINS_HTTP_READ_TIMEOUT", 10*time.Second), "timeout for reading REST requests [TRAINS_HTTP_READ_TIMEOUT]")
+	fs.DurationVar(&conf.ShutdownTimeout, "shutdown-timeout", env.Duration("TRAINS_SHUTDOWN_TIMEOUT", 20*time.Second), "time allowed for in-flight calls to finish on SIGTERM [TRAINS_SHUTDOWN_TIMEOUT]")
+	fs.IntVar(&conf.BreakerFailures, "breaker-failures", env.Int("TRAINS_BREAKER_FAILURES", breakerFailures), "consecutive transportAPI failures that open the circuit [TRAINS_BREAKER_FAILURES]")
+	fs.DurationVar(&conf.BreakerCooldown, "breaker-cooldown", env.Duration("TRAINS_BREAKER_COOLDOWN", breakerCooldown), "time the circuit stays open before a trial call [TRAINS_BREAKER_COOLDOWN]")
+	fs.StringVar(&conf.AppIdFile, "app-id-file", envOr("TRAINS_APP_ID_FILE", ".transportAppId"), "file holding the transportAPI app_id, TRANSPORTAPPID overrides it [TRAINS_APP_ID_FILE]")
+	fs.StringVar(&conf.AppKeyFile, "app-key-file", envOr("TRAINS_APP_KEY_FILE", ".transportAppKey"), "file holding the transportAPI app_key, TRANSPORTAPPKEY overrides it [TRAINS_APP_KEY_FILE]")
+	fs.StringVar(&conf.StationsCSV, "stations", envOr("TRAINS_STATIONS_CSV", STATION_NAMES_CSV), "station codes CSV [TRAINS_STATIONS_CSV]")
+	fs.StringVar(&conf.OpenAPIFile, "openapi", envOr("TRAINS_OPENAPI_JSON", OPENAPI_JSON), "OpenAPI document served at /openapi.json [TRAINS_OPENAPI_JSON]")
+	fs.StringVar(&conf.LogLevel, "log-level", envOr("TRAINS_LOG_LEVEL", "info"), "debug, info, warn or error, debug logs every transportAPI request [TRAINS_LOG_LEVEL]")
+	fs.BoolVar(&conf.LogJSON, "log-json", env.Bool("TRAINS_LOG_JSON", false), "log JSON objects rather than text [TRAINS_LOG_JSON]")
+	fs.StringVar(&conf.TraceExporter, "trace-exporter", envOr("TRAINS_TRACE_EXPORTER", "none"), "where OpenTelemetry spans go: none, stdout or otlp [TRAINS_TRACE_EXPORTER]")
+	fs.StringVar(&conf.APIKeysFile, "api-keys", envOr("TRAINS_API_KEYS_FILE", API_KEYS_FILE), "CSV of client API keys, anyone may call the server if there are none [TRAINS_API_KEYS_FILE]")
+	fs.IntVar(&conf.RateLimit, "rate-limit", env.Int("TRAINS_RATE_LIMIT", rateLimit), "calls a minute allowed for each API key without its own limit [TRAINS_RATE_LIMIT]")
+	fs.IntVar(&conf.DailyQuota, "daily-quota", env.Int("TRAINS_DAILY_QUOTA", dailyQuota), "calls a day allowed for each API key without its own quota [TRAINS_DAILY_QUOTA]")
+	fs.StringVar(&conf.GTFSRTStations, "gtfs-rt-stations", envOr("TRAINS_GTFS_RT_STATIONS", ""), "comma separated CRS codes for the GTFS-Realtime feed, empty to disable it [TRAINS_GTFS_RT_STATIONS]")
+	fs.BoolVar(&conf.HTTPClientAuth, "http-client-auth", env.Bool("TRAINS_HTTP_CLIENT_AUTH", false), "require client certificates on the REST gateway too when -tls-client-ca is given [TRAINS_HTTP_CLIENT_AUTH]")
+	if env.err != nil {
+		return nil, env.err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if (len(conf.TLSCert) > 0) != (len(conf.TLSKey) > 0) {
+		return nil, fmt.Errorf("-tls-cert and -tls-key must be given together")
+	}
+	if len(conf.TLSClientCA) > 0 && len(conf.TLSCert) == 0 {
+		return nil, fmt.Errorf("-tls-client-ca needs -tls-cert and -tls-key")
+	}
+	return conf, nil
+}
+
+func loadCertPool(ca_file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(ca_file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", ca_file)
+	}
+	return pool, nil
+}
+
+// serverTLS returns the TLS config for both listeners, or nil when TLS is off
+func (conf *config) serverTLS() (*tls.Config, error) {
+	if len(conf.TLSCert) == 0 {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(conf.TLSCert, conf.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if len(conf.TLSClientCA) > 0 {
+		pool, err := loadCertPool(conf.TLSClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// httpTLS returns the TLS config for the REST gateway listener.  Browsers, Prometheus
+// and feed readers don't have client certificates so the gateway only asks for them
+// with -http-client-auth.
+func (conf *config) httpTLS(server *tls.Config) *tls.Config {
+	if server == nil || conf.HTTPClientAuth {
+		return server
+	}
+	httpConfig := server.Clone()
+	httpConfig.ClientAuth = tls.NoClientCert
+	httpConfig.ClientCAs = nil
+	return httpConfig
+}
+
+// gatewayTLS returns the TLS config the gateway uses to call the gRPC server
+func (conf *config) gatewayTLS(server *tls.Config) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(conf.gatewayTarget())
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if len(conf.TLSCA) > 0 {
+		if tlsConfig.RootCAs, err = loadCertPool(conf.TLSCA); err != nil {
+			return nil, err
+		}
+	}
+	if server.ClientAuth == tls.RequireAndVerifyClientCert {
+		tlsConfig.Certificates = server.Certificates
+	}
+	return tlsConfig, nil
+}
+
+// gatewayTarget is the address the gateway dials to reach the gRPC listener
+func (conf *config) gatewayTarget() string {
+	host, port, err := net.SplitHostPort(conf.ListenAddr)
+	if err != nil {
+		return conf.ListenAddr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testCA signs leaf certificates for tests and writes everything out as PEM files
+type testCA struct {
+	t    *testing.T
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "trains test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{t: t, dir: t.TempDir(), cert: cert, key: key}
+	ca.file = filepath.Join(ca.dir, "ca.pem")
+	writePEM(t, ca.file, "CERTIFICATE", der)
+	return ca
+}
+
+// leaf issues a certificate for localhost, returning its certificate and key files
+func (ca *testCA) leaf(name string, serial int64, usage ...x509.ExtKeyUsage) (string, string) {
+	t := ca.t
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(ca.dir, name+".pem")
+	keyFile := filepath.Join(ca.dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	return certFile, keyFile
+}
+
+func (ca *testCA) clientTLS(certFile string, keyFile string) *tls.Config {
+	t := ca.t
+	t.Helper()
+	pool, err := loadCertPool(ca.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	if len(certFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config
+}
+
+// serveHealth runs a gRPC server with just the health service on conf's TLS settings
+func serveHealth(t *testing.T, conf *config) string {
+	t.Helper()
+	tlsConfig, err := conf.serverTLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func checkHealth(addr string, clientConfig *tls.Config) error {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientConfig)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestServerTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.leaf("server", 2, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)
+	clientCert, clientKey := ca.leaf("client", 3, x509.ExtKeyUsageClientAuth)
+	tests := []struct {
+		name       string
+		clientCA   string
+		clientCert string
+		clientKey  string
+		wantErr    bool
+	}{
+		{"TLS", "", "", "", false},
+		{"mTLS with client certificate", ca.file, clientCert, clientKey, false},
+		{"mTLS without client certificate", ca.file, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &config{TLSCert: serverCert, TLSKey: serverKey, TLSClientCA: tt.clientCA}
+			addr := serveHealth(t, conf)
+			err := checkHealth(addr, ca.clientTLS(tt.clientCert, tt.clientKey))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("health check error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPTLSClientAuth(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.leaf("server", 2, x509.ExtKeyUsageServerAuth)
+	tests := []struct {
+		name       string
+		clientAuth bool
+		wantErr    bool
+	}{
+		{"no client certificate needed by default", false, false},
+		{"client certificate needed with -http-client-auth", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &config{TLSCert: serverCert, TLSKey: serverKey, TLSClientCA: ca.file, HTTPClientAuth: tt.clientAuth}
+			tlsConfig, err := conf.serverTLS()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			ts.TLS = conf.httpTLS(tlsConfig)
+			ts.StartTLS()
+			defer ts.Close()
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: ca.clientTLS("", "")}}
+			resp, err := client.Get(ts.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GET error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+	if tlsConfig, _ := (&config{}).serverTLS(); (&config{}).httpTLS(tlsConfig) != nil {
+		t.Errorf("httpTLS without TLS should be nil")
+	}
+}
+
+func TestParseConfigRejectsBadEnvironment(t *testing.T) {
+	tests := []struct {
+		envvar string
+		value  string
+	}{
+		{"TRAINS_RATE_LIMIT", "abc"},
+		{"TRAINS_POLL_INTERVAL", "often"},
+		{"TRAINS_LOG_JSON", "perhaps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.envvar, func(t *testing.T) {
+			t.Setenv(tt.envvar, tt.value)
+			if _, err := parseConfig(nil); err == nil {
+				t.Errorf("parseConfig accepted %s=%s", tt.envvar, tt.value)
+			}
+		})
+	}
+	t.Setenv("TRAINS_RATE_LIMIT", "30")
+	conf, err := parseConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.RateLimit != 30 {
+		t.Errorf("RateLimit = %d, want 30", conf.RateLimit)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ gateway.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+REST/JSON gateway for the Trains gRPC service.
+The handlers in trains.pb.gw.go are generated by protoc-gen-grpc-gateway from the
+google.api.http options in trains.proto and proxy each HTTP request to the gRPC
+server, eg. GET /v1/trains?from=OXF&to=PAD calls GetTrains.  JSON field names are
+kept as they are in trains.proto and zero values are included so that responses
+look like the transportAPI data they come from.  The OpenAPI document generated by
+protoc-gen-swagger is served from /openapi.json.  X-Request-Id headers are passed
+through as the request ID and returned on every response.  API keys can be sent
+as X-Api-Key or Authorization: Bearer headers.
+
+Installation
+------------
+$ protoc -I../.. ... ../../trains.proto --grpc-gateway_out=logtostderr=true:.
+$ protoc -I../.. ... ../../trains.proto --swagger_out=logtostderr=true:.
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	pb ".."
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// The server is run from the grpcTrains directory
+const OPENAPI_JSON = "trains.swagger.json"
+
+// incomingHeaderMatcher passes X-Request-Id and X-Api-Key through to the gRPC call
+// as well as the headers the gateway forwards by default, including Authorization
+func incomingHeaderMatcher(key string) (string, bool) {
+	for _, header := range []string{requestIDHeader, apiKeyHeader} {
+		if strings.EqualFold(key, header) {
+			return header, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher returns the request ID as X-Request-Id rather than
+// Grpc-Metadata-X-Request-Id
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == requestIDHeader {
+		return requestIDHeader, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// newGateway returns an HTTP mux proxying REST calls to the gRPC server at grpc_addr
+// using the given transport credentials
+func newGateway(ctx context.Context, grpc_addr string, creds grpc.DialOption, openapi_file string) (*http.ServeMux, error) {
+	openapi, err := ioutil.ReadFile(openapi_file)
+	if err != nil {
+		return nil, err
+	}
+	gateway := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}),
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	)
+	opts := []grpc.DialOption{creds, grpc.WithStatsHandler(otelgrpc.NewClientHandler())}
+	if err := pb.RegisterTrainServiceHandlerFromEndpoint(ctx, gateway, grpc_addr, opts); err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapi)
+	})
+	mux.Handle("/", gateway)
+	return mux, nil
+}
@@ -0,0 +1,334 @@
+/*
+ gtfsrt.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+GTFS-Realtime TripUpdates feed served by the Go server at /gtfs-rt/trip-updates.
+Standard transit tools read live delays from a GTFS-Realtime feed rather than
+from transportAPI's JSON, so the server watches the live departures board for each
+station given with -gtfs-rt-stations and turns every departure into a TripUpdate.
+The boards are polled by the departure watcher so a station is only polled once
+however else it is being watched.
+Trips are identified by train_uid and the date the service runs on, taken from
+its timetable link, as in the static feed from the export gtfs command, so a train
+just after midnight is the same trip on boards fetched either side of it.  Each
+station a train departs from becomes a StopTimeUpdate with the expected arrival
+and departure times and their delays.  Stops are the CRS code,
+or CRS:platform once a platform is known, again as in the static feed.  A train
+cancelled at a station skips that stop and a train cancelled at every station
+polled is cancelled.  The feed is rebuilt as the boards change and served as a
+protobuf FeedMessage, or as text with ?format=text for debugging.  A station that
+can't be polled keeps its last board for up to 3 poll intervals and is then left
+out, so that a long transportAPI outage doesn't pass off old predictions as live.
+Each TripUpdate carries the time its board was fetched.
+
+Installation
+------------
+$ go run ./server -gtfs-rt-stations=OXF,RDG,PAD
+$ curl -o trip-updates.pb "http://localhost:8080/gtfs-rt/trip-updates"
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc/status"
+)
+
+const GTFS_RT_VERSION = "2.0"
+
+// Boards older than this many poll intervals are left out of the feed
+const GTFS_RT_MAX_AGE_POLLS = 3
+
+// stationBoard is the live departures board for a station and when it was fetched
+type stationBoard struct {
+	journey *TrainJourney
+	fetched time.Time
+}
+
+// tripUpdatesFeed follows the boards for the configured stations and keeps the
+// latest feed ready to serve
+type tripUpdatesFeed struct {
+	mu       sync.RWMutex
+	watcher  *departureWatcher
+	stations []string
+	interval time.Duration
+	changed  chan struct{}
+	feed     *gtfs.FeedMessage
+	data     []byte
+}
+
+func newTripUpdatesFeed(watcher *departureWatcher, stations []string, interval time.Duration) *tripUpdatesFeed {
+	return &tripUpdatesFeed{watcher: watcher, stations: stations, interval: interval, changed: make(chan struct{}, 1)}
+}
+
+// parseStationList splits a comma separated list of CRS codes
+func parseStationList(list string) []string {
+	var stations []string
+	for _, code := range strings.Split(list, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); len(code) > 0 {
+			stations = append(stations, code)
+		}
+	}
+	return stations
+}
+
+// run watches every station and rebuilds the feed whenever a board changes, and
+// every poll interval so that stale boards are dropped
+func (f *tripUpdatesFeed) run(ctx context.Context) {
+	for _, station := range f.stations {
+		go f.follow(ctx, station)
+	}
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-f.changed:
+		}
+		f.rebuild(time.Now())
+	}
+}
+
+// follow keeps the departure watcher polling a station's whole board.  The updates
+// themselves aren't needed, only that there is a new board to read.
+func (f *tripUpdatesFeed) follow(ctx context.Context, station string) {
+	for {
+		updates, unsubscribe := f.watcher.Subscribe(station, "")
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				unsubscribe()
+				return
+			case _, open = <-updates:
+				select {
+				case f.changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+		unsubscribe()
+	}
+}
+
+func (f *tripUpdatesFeed) rebuild(now time.Time) {
+	maxAge := GTFS_RT_MAX_AGE_POLLS * f.interval
+	boards := make(map[string]stationBoard)
+	for _, station := range f.stations {
+		journey, fetched, ok := f.watcher.Latest(station, "")
+		if !ok {
+			continue
+		}
+		if age := now.Sub(fetched); age > maxAge {
+			slog.Warn("Leaving stale board out of the GTFS-Realtime feed", "station_code", station, "age", age.Round(time.Second).String())
+			continue
+		}
+		boards[station] = stationBoard{journey: journey, fetched: fetched}
+	}
+	var feed *gtfs.FeedMessage
+	var data []byte
+	if len(boards) > 0 {
+		feed = newTripUpdatesMessage(f.stations, boards)
+		var err error
+		if data, err = proto.Marshal(feed); err != nil {
+			slog.Error("Cannot serialize GTFS-Realtime feed", "error", err)
+			return
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.feed = feed
+	f.data = data
+}
+
+// current returns the latest feed, or nil if there are no recent boards
+func (f *tripUpdatesFeed) current() (*gtfs.FeedMessage, []byte) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.feed, f.data
+}
+
+func gtfsRTStopId(code string, platform string) string {
+	if len(platform) == 0 {
+		return code
+	}
+	return code + ":" + platform
+}
+
+// stopTimeEvent gives the expected time and its delay, or nil if there is no estimate
+func stopTimeEvent(journey *TrainJourney, aimed string, expected string) *gtfs.TripUpdate_StopTimeEvent {
+	e, ok := boardTime(journey, expected)
+	if !ok {
+		return nil
+	}
+	event := &gtfs.TripUpdate_StopTimeEvent{Time: proto.Int64(e.Unix())}
+	if a, ok := boardTime(journey, aimed); ok {
+		event.Delay = proto.Int32(int32(e.Sub(a) / time.Second))
+	}
+	return event
+}
+
+func newStopTimeUpdate(journey *TrainJourney, departure TrainDeparture) *gtfs.TripUpdate_StopTimeUpdate {
+	update := &gtfs.TripUpdate_StopTimeUpdate{StopId: proto.String(gtfsRTStopId(journey.StationCode, departure.Platform))}
+	if strings.EqualFold(departure.Status, "CANCELLED") {
+		update.ScheduleRelationship = gtfs.TripUpdate_StopTimeUpdate_SKIPPED.Enum()
+		return update
+	}
+	update.Arrival = stopTimeEvent(journey, departure.AimedArrival, departure.ExpectedArrival)
+	update.Departure = stopTimeEvent(journey, departure.AimedDeparture, departure.ExpectedDeparture)
+	if update.Arrival == nil && update.Departure == nil {
+		update.ScheduleRelationship = gtfs.TripUpdate_StopTimeUpdate_NO_DATA.Enum()
+	}
+	return update
+}
+
+// The date in a departure's service timetable link is the day the service runs on
+var timetableDate = regexp.MustCompile(`/(\d{4}-\d{2}-\d{2})/timetable\.json`)
+
+// tripDate is the day a departure's service runs on, or the board's date if its
+// timetable link doesn't say
+func tripDate(journey *TrainJourney, departure TrainDeparture) string {
+	if match := timetableDate.FindStringSubmatch(departure.ServiceTimetable.Url); match != nil {
+		return match[1]
+	}
+	return journey.Date
+}
+
+// updateTime orders the stops of a trip by when the train is due at them
+func updateTime(journey *TrainJourney, departure TrainDeparture) time.Time {
+	for _, clock := range []string{departure.AimedArrival, departure.AimedDeparture} {
+		if t, ok := boardTime(journey, clock); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// newTripUpdatesMessage builds a full feed with one TripUpdate per train per day.
+// Each TripUpdate is stamped with when its newest board was fetched and the feed
+// with when the newest board of all was fetched.
+func newTripUpdatesMessage(stations []string, boards map[string]stationBoard) *gtfs.FeedMessage {
+	type tripStop struct {
+		due    time.Time
+		update *gtfs.TripUpdate_StopTimeUpdate
+	}
+	trips := make(map[string]*gtfs.TripUpdate)
+	stops := make(map[string][]tripStop)
+	var tripIds []string
+	var newest time.Time
+	for _, station := range stations {
+		board, ok := boards[station]
+		if !ok {
+			continue
+		}
+		journey := board.journey
+		if board.fetched.After(newest) {
+			newest = board.fetched
+		}
+		for _, departure := range journey.Departures.All {
+			if len(departure.TrainUid) == 0 {
+				continue
+			}
+			date := tripDate(journey, departure)
+			tripId := departure.TrainUid + "_" + date
+			trip, ok := trips[tripId]
+			if !ok {
+				trip = &gtfs.TripUpdate{
+					Trip: &gtfs.TripDescriptor{
+						TripId:    proto.String(tripId),
+						StartDate: proto.String(strings.Replace(date, "-", "", -1)),
+					},
+				}
+				trips[tripId] = trip
+				tripIds = append(tripIds, tripId)
+			}
+			if fetched := uint64(board.fetched.Unix()); fetched > trip.GetTimestamp() {
+				trip.Timestamp = proto.Uint64(fetched)
+			}
+			update := newStopTimeUpdate(journey, departure)
+			stops[tripId] = append(stops[tripId], tripStop{due: updateTime(journey, departure), update: update})
+		}
+	}
+	feed := &gtfs.FeedMessage{
+		Header: &gtfs.FeedHeader{
+			GtfsRealtimeVersion: proto.String(GTFS_RT_VERSION),
+			Incrementality:      gtfs.FeedHeader_FULL_DATASET.Enum(),
+			Timestamp:           proto.Uint64(uint64(newest.Unix())),
+		},
+	}
+	for _, tripId := range tripIds {
+		trip := trips[tripId]
+		sort.SliceStable(stops[tripId], func(i, j int) bool { return stops[tripId][i].due.Before(stops[tripId][j].due) })
+		cancelled := true
+		for _, stop := range stops[tripId] {
+			trip.StopTimeUpdate = append(trip.StopTimeUpdate, stop.update)
+			if stop.update.GetScheduleRelationship() != gtfs.TripUpdate_StopTimeUpdate_SKIPPED {
+				cancelled = false
+			}
+		}
+		if cancelled {
+			trip.Trip.ScheduleRelationship = gtfs.TripDescriptor_CANCELED.Enum()
+			trip.StopTimeUpdate = nil
+		} else {
+			trip.Trip.ScheduleRelationship = gtfs.TripDescriptor_SCHEDULED.Enum()
+		}
+		feed.Entity = append(feed.Entity, &gtfs.FeedEntity{Id: proto.String(tripId), TripUpdate: trip})
+	}
+	return feed
+}
+
+// newTripUpdatesHandler serves the latest feed.  Feed readers often can't add
+// headers so the API key may also be given as the key query parameter.
+func newTripUpdatesHandler(f *tripUpdatesFeed, auth *authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withRequestID(r.Context(), r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID(ctx))
+		key := bearerKey(r.Header.Get(apiKeyHeader), r.Header.Get("Authorization"))
+		if len(key) == 0 {
+			key = r.URL.Query().Get("key")
+		}
+		if err := auth.Check(key); err != nil {
+			http.Error(w, status.Convert(err).Message(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+		feed, data := f.current()
+		if feed == nil {
+			http.Error(w, "GTFS-Realtime feed has no recent departures boards", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, err := w.Write([]byte(proto.MarshalTextString(feed)))
+			if err != nil {
+				slog.WarnContext(ctx, "Cannot write GTFS-Realtime feed", "error", err)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		if _, err := w.Write(data); err != nil {
+			slog.WarnContext(ctx, "Cannot write GTFS-Realtime feed", "error", err)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+)
+
+func testBoard(station string, fetched time.Time, departures ...TrainDeparture) stationBoard {
+	journey := &TrainJourney{StationCode: station, Date: "2019-10-26", TimeOfDay: "23:30"}
+	journey.Departures.All = departures
+	return stationBoard{journey: journey, fetched: fetched}
+}
+
+func findTrip(feed *gtfs.FeedMessage, tripId string) *gtfs.TripUpdate {
+	for _, entity := range feed.Entity {
+		if entity.GetId() == tripId {
+			return entity.TripUpdate
+		}
+	}
+	return nil
+}
+
+func TestNewTripUpdatesMessage(t *testing.T) {
+	early := time.Date(2019, 10, 26, 23, 30, 0, 0, london)
+	late := early.Add(time.Minute)
+	boards := map[string]stationBoard{
+		"RDG": testBoard("RDG", late,
+			TrainDeparture{TrainUid: "C1", Platform: "4", AimedDeparture: "23:40", ExpectedDeparture: "23:42", Status: "LATE"},
+			TrainDeparture{TrainUid: "C2", AimedDeparture: "23:50", Status: "CANCELLED"},
+			TrainDeparture{TrainUid: "C3", AimedDeparture: "23:55", Status: "NO REPORT"},
+			TrainDeparture{TrainUid: "C4", AimedDeparture: "23:58", ExpectedDeparture: "00:03", Status: "LATE"},
+			TrainDeparture{TrainUid: "C5", AimedDeparture: "00:10", ExpectedDeparture: "00:10", Status: "ON TIME",
+				ServiceTimetable: TrainTimetable{Url: UPSTREAM_URL + "/service/train_uid:C5/2019-10-27/timetable.json?live=true"}}),
+		"OXF": testBoard("OXF", early,
+			TrainDeparture{TrainUid: "C1", AimedArrival: "23:15", AimedDeparture: "23:16", ExpectedDeparture: "23:16"},
+			TrainDeparture{TrainUid: "C2", AimedDeparture: "23:20", Status: "CANCELLED"}),
+	}
+	feed := newTripUpdatesMessage([]string{"RDG", "OXF"}, boards)
+	if got := feed.Header.GetTimestamp(); got != uint64(late.Unix()) {
+		t.Errorf("header timestamp = %d, want the newest board %d", got, late.Unix())
+	}
+
+	tests := []struct {
+		tripId       string
+		startDate    string
+		relationship gtfs.TripDescriptor_ScheduleRelationship
+		stops        []string
+		delays       []int32
+		timestamp    time.Time
+	}{
+		// OXF comes first as the train is due there first, whatever order the stations are in
+		{"C1_2019-10-26", "20191026", gtfs.TripDescriptor_SCHEDULED, []string{"OXF", "RDG:4"}, []int32{0, 120}, late},
+		{"C2_2019-10-26", "20191026", gtfs.TripDescriptor_CANCELED, nil, nil, late},
+		{"C3_2019-10-26", "20191026", gtfs.TripDescriptor_SCHEDULED, []string{"RDG"}, nil, late},
+		// Expected after midnight is on the next day, not 23 hours early
+		{"C4_2019-10-26", "20191026", gtfs.TripDescriptor_SCHEDULED, []string{"RDG"}, []int32{300}, late},
+		// Runs tomorrow though it is on tonight's board
+		{"C5_2019-10-27", "20191027", gtfs.TripDescriptor_SCHEDULED, []string{"RDG"}, []int32{0}, late},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tripId, func(t *testing.T) {
+			trip := findTrip(feed, tt.tripId)
+			if trip == nil {
+				t.Fatalf("no TripUpdate for %s", tt.tripId)
+			}
+			if got := trip.Trip.GetScheduleRelationship(); got != tt.relationship {
+				t.Errorf("schedule relationship = %v, want %v", got, tt.relationship)
+			}
+			if got := trip.Trip.GetStartDate(); got != tt.startDate {
+				t.Errorf("start date = %q, want %s", got, tt.startDate)
+			}
+			if got := trip.GetTimestamp(); got != uint64(tt.timestamp.Unix()) {
+				t.Errorf("timestamp = %d, want %d", got, tt.timestamp.Unix())
+			}
+			if len(trip.StopTimeUpdate) != len(tt.stops) {
+				t.Fatalf("got %d stop time updates, want %d", len(trip.StopTimeUpdate), len(tt.stops))
+			}
+			for i, update := range trip.StopTimeUpdate {
+				if update.GetStopId() != tt.stops[i] {
+					t.Errorf("stop %d = %q, want %q", i, update.GetStopId(), tt.stops[i])
+				}
+				if tt.delays == nil {
+					if update.GetScheduleRelationship() != gtfs.TripUpdate_StopTimeUpdate_NO_DATA {
+						t.Errorf("stop %d without an estimate is %v, want NO_DATA", i, update.GetScheduleRelationship())
+					}
+					continue
+				}
+				if got := update.Departure.GetDelay(); got != tt.delays[i] {
+					t.Errorf("stop %d delay = %d, want %d", i, got, tt.delays[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTripUpdatesFeedDropsStaleBoards(t *testing.T) {
+	watcher := newDepartureWatcher(time.Minute)
+	fetched := time.Now()
+	journey := testBoard("RDG", fetched, TrainDeparture{TrainUid: "C1", AimedDeparture: "10:00"}).journey
+	watcher.routes["RDG-"] = &routeWatch{from: "RDG", journey: journey, fetched: fetched}
+	f := newTripUpdatesFeed(watcher, []string{"RDG"}, time.Minute)
+
+	f.rebuild(fetched.Add(2 * time.Minute))
+	if feed, _ := f.current(); feed == nil || len(feed.Entity) != 1 {
+		t.Fatalf("feed from a 2 minute old board = %v, want one trip", feed)
+	}
+	f.rebuild(fetched.Add(10 * time.Minute))
+	if feed, data := f.current(); feed != nil || data != nil {
+		t.Errorf("feed from a 10 minute old board = %v, want none", feed)
+	}
+}
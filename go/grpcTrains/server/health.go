@@ -0,0 +1,70 @@
+/*
+ health.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Standard grpc.health.v1 reporting for the Trains server.
+Both the overall server ("") and trains.TrainService report NOT_SERVING while we
+can't usefully answer requests: when the transportAPI credentials are missing or
+while the upstream circuit is open and its cooldown hasn't passed yet.  Everything is NOT_SERVING once shutdown has
+started so that load balancers move traffic away before we stop.
+
+Installation
+------------
+$ grpc_health_probe -addr=localhost:8001 -service=trains.TrainService
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const serviceName = "trains.TrainService"
+
+type healthReporter struct {
+	mu          sync.Mutex
+	server      *health.Server
+	credentials bool
+	circuitOpen bool
+}
+
+func newHealthReporter(credentials bool) *healthReporter {
+	h := &healthReporter{server: health.NewServer(), credentials: credentials}
+	h.update()
+	return h
+}
+
+func (h *healthReporter) SetCircuitOpen(open bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.circuitOpen = open
+	h.update()
+}
+
+// Shutdown reports NOT_SERVING from now on whatever else happens
+func (h *healthReporter) Shutdown() {
+	h.server.Shutdown()
+}
+
+// update must be called with h.mu held unless h is not shared yet
+func (h *healthReporter) update() {
+	status := healthpb.HealthCheckResponse_SERVING
+	if !h.credentials || h.circuitOpen {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.server.SetServingStatus("", status)
+	h.server.SetServingStatus(serviceName, status)
+}
@@ -0,0 +1,202 @@
+/*
+ logging.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Structured logging for the Trains server using log/slog.
+-log-level picks debug, info, warn or error and -log-json switches from text to
+one JSON object per line.  Every gRPC call carries a request ID, taken from the
+x-request-id metadata when the caller sends one and generated otherwise.  It is
+returned in the response header, added to every log line written for the call and
+sent on to transportAPI so that a slow board can be followed end to end.
+transportAPI credentials travel in the query string, including inside the
+service_timetable links it hands back, so app_id and app_key values are masked in
+anything logged and in upstream errors before they can reach a client.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const requestIDHeader = "x-request-id"
+
+// key is the API key the board takes as a query parameter
+var credentialParam = regexp.MustCompile(`(?i)\b(app_id|app_key|key)=[^&\s"']*`)
+
+// redactURL masks credentials in a URL or in any text containing one
+func redactURL(text string) string {
+	return credentialParam.ReplaceAllString(text, "${1}=REDACTED")
+}
+
+// redactedError hides credentials in the message of an upstream error
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return redactURL(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return redactedError{err}
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redactURL(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(redactURL(err.Error()))
+		}
+	}
+	return a
+}
+
+// requestIDHandler adds the request ID and trace ID from the context to every record
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestID(ctx); len(id) > 0 {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+// setupLogging makes a redacting slog logger the default, which also takes over
+// output from the log package
+func setupLogging(level string, json bool) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if json {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(requestIDHandler{handler}))
+	// The log package is left for fatal errors
+	slog.SetLogLoggerLevel(slog.LevelError)
+	return nil
+}
+
+type requestIDKey struct{}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withRequestID stores id in ctx, generating a new one if id is empty
+func withRequestID(ctx context.Context, id string) context.Context {
+	id = strings.TrimSpace(id)
+	if len(id) == 0 || len(id) > 64 {
+		id = newRequestID()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// incomingRequestID picks up the caller's request ID from the gRPC metadata
+func incomingRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	return withRequestID(ctx, id)
+}
+
+func logRPC(ctx context.Context, method string, start time.Time, err error) {
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	attrs := []any{"method", method, "code", status.Code(err).String(), "duration", time.Since(start)}
+	if err != nil {
+		attrs = append(attrs, "error", status.Convert(err).Message())
+	}
+	slog.Log(ctx, level, "rpc", attrs...)
+}
+
+func loggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = incomingRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID(ctx)))
+	resp, err := handler(ctx, req)
+	logRPC(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// requestIDStream hands the stream handler a context carrying the request ID
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s requestIDStream) Context() context.Context {
+	return s.ctx
+}
+
+func loggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := incomingRequestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDHeader, requestID(ctx)))
+	err := handler(srv, requestIDStream{ServerStream: ss, ctx: ctx})
+	logRPC(ctx, info.FullMethod, start, err)
+	return err
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"http://transportapi.com/v3/uk/train/station/TWY/live.json?app_id=abc&app_key=def&live=true",
+			"http://transportapi.com/v3/uk/train/station/TWY/live.json?app_id=REDACTED&app_key=REDACTED&live=true"},
+		{"GET /board?from=TWY&to=PAD&key=secret", "GET /board?from=TWY&to=PAD&key=REDACTED"},
+		{"/board?KEY=secret&from=TWY", "/board?KEY=REDACTED&from=TWY"},
+		{`Get "http://host/x?app_key=def": timeout`, `Get "http://host/x?app_key=REDACTED": timeout`},
+		// Only whole parameter names
+		{"/board?monkey=business", "/board?monkey=business"},
+		{"no credentials here", "no credentials here"},
+	}
+	for _, tt := range tests {
+		if got := redactURL(tt.text); got != tt.want {
+			t.Errorf("redactURL(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
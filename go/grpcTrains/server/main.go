@@ -25,7 +25,8 @@ $ protoc -I../..																	# generate in current grpcTrains directory
 		 -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway
 		 -I$GOPATH/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis
 		 ../../trains.proto --go_out=plugins=grpc:.
-$ go run -ldflags="-s -w" server/main.go											# run server in current grpcTrains directory
+		 --grpc-gateway_out=logtostderr=true:. --swagger_out=logtostderr=true:.		# REST gateway and OpenAPI
+$ go run -ldflags="-s -w" ./server												# run server in current grpcTrains directory
 
 Version
 -------
@@ -37,35 +38,314 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	pb ".."
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	port = ":8001"
+	port     = ":8001"
+	httpPort = ":8080"
 )
 
 // server is used to implement trains.TrainService.
-type server struct{}
+type server struct {
+	watcher  *departureWatcher
+	stations *stationRegistry
+	done     chan struct{}
+}
+
+func (s *server) validateRequest(in *pb.TrainRequest) error {
+	if len(in.From) != 3 || len(in.To) != 3 {
+		return status.Errorf(codes.InvalidArgument, "from and to must be three letter CRS codes, got %q and %q", in.From, in.To)
+	}
+	for _, code := range []string{in.From, in.To} {
+		if _, ok := s.stations.Lookup(code); !ok {
+			return status.Errorf(codes.NotFound, "unknown station %q", code)
+		}
+	}
+	return nil
+}
 
 // GetTrains implements trains.TrainService.GetTrains
 func (s *server) GetTrains(ctx context.Context, in *pb.TrainRequest) (*pb.TrainResponse, error) {
-	log.Printf("Received: %v", in)
-	//return &pb.TrainResponse{station_name: "Test", station_code: "TST"}, nil
-	return &pb.TrainResponse{}, nil
+	slog.DebugContext(ctx, "Received", "request", in.String())
+	if err := s.validateRequest(in); err != nil {
+		return nil, err
+	}
+	response, _, err := fetchTrains(ctx, in.From, in.To, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%v", err)
+	}
+	if len(response.DestName) == 0 {
+		dest, _ := s.stations.Lookup(in.To)
+		response.DestName = dest.StationName
+	}
+	return response, nil
+}
+
+// WatchDepartures implements trains.TrainService.WatchDepartures
+func (s *server) WatchDepartures(in *pb.TrainRequest, stream pb.TrainService_WatchDeparturesServer) error {
+	slog.DebugContext(stream.Context(), "Watching", "request", in.String())
+	if err := s.validateRequest(in); err != nil {
+		return err
+	}
+	updates, unsubscribe := s.watcher.Subscribe(in.From, in.To)
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case update, ok := <-updates:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client fell too far behind the departure updates")
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ListStations implements trains.TrainService.ListStations
+func (s *server) ListStations(ctx context.Context, in *pb.ListStationsRequest) (*pb.ListStationsResponse, error) {
+	response, err := s.stations.List(in.PageSize, in.PageToken)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return response, nil
+}
+
+// GetStation implements trains.TrainService.GetStation
+func (s *server) GetStation(ctx context.Context, in *pb.GetStationRequest) (*pb.Station, error) {
+	station, ok := s.stations.Lookup(in.CrsCode)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown station %q", in.CrsCode)
+	}
+	return station, nil
+}
+
+// SearchStations implements trains.TrainService.SearchStations
+func (s *server) SearchStations(ctx context.Context, in *pb.SearchStationsRequest) (*pb.SearchStationsResponse, error) {
+	if len(strings.TrimSpace(in.Query)) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "query must not be empty")
+	}
+	response, err := s.stations.Search(in.Query, in.PageSize, in.PageToken)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return response, nil
+}
+
+// GetTrainsV2 implements trains.TrainService.GetTrainsV2
+func (s *server) GetTrainsV2(ctx context.Context, in *pb.TrainRequest) (*pb.TrainResponseV2, error) {
+	slog.DebugContext(ctx, "Received", "request", in.String())
+	if err := s.validateRequest(in); err != nil {
+		return nil, err
+	}
+	journey, stops, err := fetchJourney(ctx, in.From, in.To, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%v", err)
+	}
+	dest, _ := s.stations.Lookup(in.To)
+	return newTrainResponseV2(journey, in.To, dest.StationName, stops), nil
+}
+
+// GetService implements trains.TrainService.GetService
+func (s *server) GetService(ctx context.Context, in *pb.ServiceRequest) (*pb.ServiceResponse, error) {
+	slog.DebugContext(ctx, "Received", "request", in.String())
+	service, err := fetchService(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return newServiceResponse(service), nil
+}
+
+// GetServiceV2 implements trains.TrainService.GetServiceV2
+func (s *server) GetServiceV2(ctx context.Context, in *pb.ServiceRequest) (*pb.ServiceResponseV2, error) {
+	slog.DebugContext(ctx, "Received", "request", in.String())
+	service, err := fetchService(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return newServiceResponseV2(service), nil
+}
+
+func fetchService(ctx context.Context, in *pb.ServiceRequest) (*TrainStops, error) {
+	if len(in.TrainUid) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "train_uid must not be empty")
+	}
+	date, ok := serviceDate(in.Date)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "date must be YYYY-MM-DD, got %q", in.Date)
+	}
+	service, err := getServiceTimetable(ctx, in.TrainUid, date)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%v", err)
+	}
+	if len(service.Stops) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no timetable for train %s on %s", in.TrainUid, date)
+	}
+	return service, nil
+}
+
+// serveGateway runs the REST gateway until it is shut down
+func serveGateway(httpServer *http.Server) {
+	slog.Info("REST gateway listening", "addr", httpServer.Addr)
+	var err error
+	if httpServer.TLSConfig != nil {
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("failed to serve gateway: %v", err)
+	}
+}
+
+// shutdown drains in-flight calls, giving up and closing everything after the timeout
+func shutdown(s *grpc.Server, trains *server, httpServer *http.Server, reporter *healthReporter, timeout time.Duration) {
+	slog.Info("Shutting down, waiting for calls to finish", "timeout", timeout.String())
+	reporter.Shutdown()
+	close(trains.done)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Warn("REST gateway did not shut down cleanly", "error", err)
+		}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Timed out waiting for calls to finish")
+		s.Stop()
+	}
 }
 
 func main() {
-	lis, err := net.Listen("tcp", port)
+	conf, err := parseConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if err := setupLogging(conf.LogLevel, conf.LogJSON); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	shutdownTracing, err := setupTracing(context.Background(), conf.TraceExporter)
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
+	UPSTREAM_URL = strings.TrimRight(conf.UpstreamURL, "/")
+	UPSTREAM_TIMEOUT = conf.UpstreamTimeout
+	upstreamClient = newUpstreamClient(UPSTREAM_TIMEOUT)
+	// Missing credentials leave the server up but reporting NOT_SERVING
+	if APP_ID, err = readCred("TRANSPORTAPPID", conf.AppIdFile); err != nil {
+		slog.Warn("failed to read credentials", "error", err)
+	}
+	if APP_KEY, err = readCred("TRANSPORTAPPKEY", conf.AppKeyFile); err != nil {
+		slog.Warn("failed to read credentials", "error", err)
+	}
+	reporter := newHealthReporter(len(APP_ID) > 0 && len(APP_KEY) > 0)
+	upstreamBreaker = newCircuitBreaker(conf.BreakerFailures, conf.BreakerCooldown, reporter.SetCircuitOpen)
+	stations, err := loadStations(conf.StationsCSV)
+	if err != nil {
+		log.Fatalf("failed to load stations: %v", err)
+	}
+	auth, err := loadAPIKeys(conf.APIKeysFile, conf.RateLimit, conf.DailyQuota)
+	if err != nil {
+		log.Fatalf("failed to load API keys: %v", err)
+	}
+	if !auth.Enabled() {
+		slog.Warn("No API keys configured, anyone can call the server")
+	}
+	tlsConfig, err := conf.serverTLS()
+	if err != nil {
+		log.Fatalf("failed to load TLS configuration: %v", err)
+	}
+	lis, err := net.Listen("tcp", conf.ListenAddr)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	s := grpc.NewServer()
-	pb.RegisterTrainServiceServer(s, &server{})
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(loggingUnaryInterceptor, metricsUnaryInterceptor, auth.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(loggingStreamInterceptor, metricsStreamInterceptor, auth.StreamInterceptor),
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+	}
+	gatewayCreds := grpc.WithInsecure()
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		clientConfig, err := conf.gatewayTLS(tlsConfig)
+		if err != nil {
+			log.Fatalf("failed to load TLS configuration: %v", err)
+		}
+		gatewayCreds = grpc.WithTransportCredentials(credentials.NewTLS(clientConfig))
+	}
+	s := grpc.NewServer(opts...)
+	trains := &server{watcher: newDepartureWatcher(conf.PollInterval), stations: stations, done: make(chan struct{})}
+	pb.RegisterTrainServiceServer(s, trains)
+	healthpb.RegisterHealthServer(s, reporter.server)
+	reflection.Register(s)
+	var httpServer *http.Server
+	if len(conf.HTTPAddr) > 0 {
+		gateway, err := newGateway(context.Background(), conf.gatewayTarget(), gatewayCreds, conf.OpenAPIFile)
+		if err != nil {
+			log.Fatalf("failed to start gateway: %v", err)
+		}
+		gateway.Handle("/board", newBoardHandler(trains, auth))
+		gateway.Handle("/metrics", metricsHandler())
+		if gtfsStations := parseStationList(conf.GTFSRTStations); len(gtfsStations) > 0 {
+			for _, code := range gtfsStations {
+				if _, ok := stations.Lookup(code); !ok {
+					log.Fatalf("invalid configuration: unknown GTFS-Realtime station %s", code)
+				}
+			}
+			feed := newTripUpdatesFeed(trains.watcher, gtfsStations, conf.PollInterval)
+			go feed.run(context.Background())
+			gateway.Handle("/gtfs-rt/trip-updates", newTripUpdatesHandler(feed, auth))
+		}
+		httpServer = &http.Server{Addr: conf.HTTPAddr, Handler: traceHandler(gateway), TLSConfig: conf.httpTLS(tlsConfig), ReadTimeout: conf.HTTPReadTimeout}
+		go serveGateway(httpServer)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		<-signals
+		shutdown(s, trains, httpServer, reporter, conf.ShutdownTimeout)
+		close(stopped)
+	}()
+	slog.Info("gRPC server listening", "addr", conf.ListenAddr)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
+	<-stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
 }
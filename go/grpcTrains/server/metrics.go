@@ -0,0 +1,137 @@
+/*
+ metrics.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Prometheus metrics for the Trains server, served from /metrics on the HTTP port.
+gRPC calls are counted and timed by interceptors, labelled with the method and
+status code, and the HTML board's calls are counted the same way as "/board".  Calls to transportAPI are counted and timed by the HTTP transport
+given to grequests, labelled with the endpoint and HTTP status.  We also count
+hits and misses on the timetables reused between polls and record how many
+timetable calls each board fans out to.  Some useful queries:
+  rate(trains_grpc_requests_total{code!="OK"}[5m])
+  histogram_quantile(0.9, rate(trains_grpc_request_duration_seconds_bucket[5m]))
+  rate(trains_timetable_cache_requests_total{result="hit"}[5m]) / rate(trains_timetable_cache_requests_total[5m])
+
+Installation
+------------
+$ go get github.com/prometheus/client_golang/prometheus
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trains_grpc_requests_total",
+		Help: "gRPC calls handled, by method and status code.",
+	}, []string{"method", "code"})
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trains_grpc_request_duration_seconds",
+		Help:    "Time taken to handle gRPC calls, by method.  Streams are timed until they end.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method"})
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trains_upstream_requests_total",
+		Help: "Requests made to transportAPI, by endpoint and HTTP status, or error when no response came back.",
+	}, []string{"endpoint", "status"})
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trains_upstream_request_duration_seconds",
+		Help:    "Time taken by transportAPI requests, by endpoint.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"endpoint"})
+	timetableCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trains_timetable_cache_requests_total",
+		Help: "Train timetables needed for a board, by whether they were already known (hit) or fetched (miss).",
+	}, []string{"result"})
+	timetableFanout = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "trains_timetable_fanout",
+		Help:    "Number of timetable requests made concurrently for one board.",
+		Buckets: []float64{0, 1, 2, 4, 6, 8, 10, 15, 20, 30},
+	})
+)
+
+func init() {
+	prometheus.MustRegister(grpcRequests, grpcDuration, upstreamRequests, upstreamDuration, timetableCache, timetableFanout)
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeRPC(info.FullMethod, start, err)
+	return resp, err
+}
+
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observeRPC(info.FullMethod, start, err)
+	return err
+}
+
+// upstreamEndpoint names the transportAPI endpoint from a request path so that
+// station codes and train_uids don't end up in the labels
+func upstreamEndpoint(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/live.json"):
+		return "station_live"
+	case strings.Contains(path, "/station/") && strings.HasSuffix(path, "/timetable.json"):
+		return "station_timetable"
+	case strings.Contains(path, "/service/"):
+		return "service_timetable"
+	}
+	return "other"
+}
+
+// metricsTransport counts and times every request made to transportAPI
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := upstreamEndpoint(req.URL.Path)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	upstreamDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	upstreamRequests.WithLabelValues(endpoint, code).Inc()
+	return resp, err
+}
+
+// newUpstreamClient returns the HTTP client used for all transportAPI requests
+func newUpstreamClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: metricsTransport{next: http.DefaultTransport}}
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
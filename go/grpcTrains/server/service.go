@@ -0,0 +1,75 @@
+/*
+ service.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Support for the GetService RPC which returns the calling pattern of a single train.
+This uses the same service timetable endpoint that the departure boards link to,
+so a client that already has a train_uid can show its whole journey with aimed
+and expected times at every stop without asking for a board again.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"time"
+
+	pb ".."
+)
+
+const DATE_FORMAT = "2006-01-02"
+
+// serviceDate defaults an empty date to today and checks anything else is YYYY-MM-DD
+func serviceDate(date string) (string, bool) {
+	if len(date) == 0 {
+		return time.Now().Format(DATE_FORMAT), true
+	}
+	if _, err := time.Parse(DATE_FORMAT, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+func newServiceResponse(service *TrainStops) *pb.ServiceResponse {
+	response := &pb.ServiceResponse{
+		TrainUid:        service.TrainUid,
+		Service:         service.Service,
+		Headcode:        service.Headcode,
+		TrainStatus:     service.TrainStatus,
+		Operator:        service.Operator,
+		OperatorName:    service.OperatorName,
+		OriginName:      service.OriginName,
+		DestinationName: service.DestinationName,
+		Date:            service.Date,
+		Category:        service.Category,
+	}
+	for _, stop := range service.Stops {
+		response.Stops = append(response.Stops, &pb.ServiceResponse_ServiceStop{
+			StationCode:           stop.StationCode,
+			TiplocCode:            stop.TiplocCode,
+			StationName:           stop.StationName,
+			StopType:              stop.StopType,
+			Platform:              stop.Platform,
+			AimedArrivalTime:      stop.AimedArrival,
+			AimedDepartureTime:    stop.AimedDeparture,
+			AimedPassTime:         stop.AimedPass,
+			ExpectedArrivalTime:   stop.ExpectedArrival,
+			ExpectedDepartureTime: stop.ExpectedDeparture,
+			ExpectedPassTime:      stop.ExpectedPass,
+			Status:                stop.Status,
+		})
+	}
+	return response
+}
@@ -0,0 +1,241 @@
+/*
+ stations.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Station registry behind the ListStations, GetStation and SearchStations RPCs.
+Stations are loaded once from the same station_codes.csv used by trainsClient.go
+and kept sorted by name so that page tokens are stable between calls.
+Page tokens are simply the offset of the next result.  Searches score every
+station against the query: an exact CRS code match first, then name prefix,
+word prefix and substring matches, then names containing the query letters in
+order and finally names within a couple of typos of it.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb ".."
+)
+
+// The server is run from the grpcTrains directory
+const STATION_NAMES_CSV = "../station_codes.csv"
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxTypos        = 2
+)
+
+type stationRegistry struct {
+	stations []*pb.Station
+	byCode   map[string]*pb.Station
+}
+
+type stationMatch struct {
+	station *pb.Station
+	score   int
+}
+
+func loadStations(csvFile string) (*stationRegistry, error) {
+	file, err := os.Open(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	registry := &stationRegistry{byCode: make(map[string]*pb.Station)}
+	reader := csv.NewReader(file)
+	header := true
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %v", csvFile, err)
+		}
+		if header {
+			header = false
+			continue
+		}
+		if len(line) < 2 || len(line[1]) != 3 {
+			continue
+		}
+		station := &pb.Station{StationName: strings.TrimSpace(line[0]), CrsCode: strings.ToUpper(line[1])}
+		registry.stations = append(registry.stations, station)
+		registry.byCode[station.CrsCode] = station
+	}
+	sort.SliceStable(registry.stations, func(i, j int) bool {
+		return registry.stations[i].StationName < registry.stations[j].StationName
+	})
+	return registry, nil
+}
+
+// Lookup returns the station for a CRS code in any case
+func (r *stationRegistry) Lookup(crs_code string) (*pb.Station, bool) {
+	station, ok := r.byCode[strings.ToUpper(crs_code)]
+	return station, ok
+}
+
+// page returns one page of stations along with the token for the next page
+func page(stations []*pb.Station, page_size int32, page_token string) ([]*pb.Station, string, error) {
+	offset := 0
+	if len(page_token) > 0 {
+		n, err := strconv.Atoi(page_token)
+		if err != nil || n < 0 || n > len(stations) {
+			return nil, "", fmt.Errorf("invalid page token %q", page_token)
+		}
+		offset = n
+	}
+	size := int(page_size)
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	end := offset + size
+	if end >= len(stations) {
+		return stations[offset:], "", nil
+	}
+	return stations[offset:end], strconv.Itoa(end), nil
+}
+
+func (r *stationRegistry) List(page_size int32, page_token string) (*pb.ListStationsResponse, error) {
+	stations, next, err := page(r.stations, page_size, page_token)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListStationsResponse{Stations: stations, NextPageToken: next, TotalSize: int32(len(r.stations))}, nil
+}
+
+func (r *stationRegistry) Search(query string, page_size int32, page_token string) (*pb.SearchStationsResponse, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches []stationMatch
+	for _, station := range r.stations {
+		if score := matchStation(station, query); score > 0 {
+			matches = append(matches, stationMatch{station: station, score: score})
+		}
+	}
+	// Stations are already in name order so a stable sort keeps ties alphabetical
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	ranked := make([]*pb.Station, len(matches))
+	for i, match := range matches {
+		ranked[i] = match.station
+	}
+	stations, next, err := page(ranked, page_size, page_token)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SearchStationsResponse{Stations: stations, NextPageToken: next, TotalSize: int32(len(ranked))}, nil
+}
+
+// matchStation scores how well a lower case query matches a station, 0 meaning not at all
+func matchStation(station *pb.Station, query string) int {
+	name := strings.ToLower(station.StationName)
+	switch {
+	case len(query) == 0:
+		return 0
+	case strings.ToLower(station.CrsCode) == query:
+		return 100
+	case name == query:
+		return 95
+	case strings.HasPrefix(name, query):
+		return 90
+	}
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '-' || r == '(' || r == ')' })
+	for _, word := range words {
+		if strings.HasPrefix(word, query) {
+			return 80
+		}
+	}
+	if strings.Contains(name, query) {
+		return 70
+	}
+	if gaps, ok := subsequenceGaps(name, query); ok && gaps < len(query) {
+		return 60 - gaps
+	}
+	// Allow a couple of typos against the start of the name or any word in it
+	best := maxTypos + 1
+	for _, candidate := range append([]string{name}, words...) {
+		if len(candidate) > len(query) {
+			candidate = candidate[:len(query)]
+		}
+		if d := levenshtein(candidate, query); d < best {
+			best = d
+		}
+	}
+	if best <= maxTypos && len(query) > 2*best {
+		return 30 - 10*best
+	}
+	return 0
+}
+
+// subsequenceGaps checks the query letters appear in order in the name, counting the
+// number of places the match has to skip ahead
+func subsequenceGaps(name string, query string) (int, bool) {
+	gaps, i := 0, 0
+	skipped := false
+	for j := 0; j < len(name) && i < len(query); j++ {
+		if name[j] == query[i] {
+			if skipped && i > 0 {
+				gaps++
+			}
+			skipped = false
+			i++
+		} else {
+			skipped = true
+		}
+	}
+	return gaps, i == len(query)
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
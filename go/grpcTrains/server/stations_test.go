@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	pb ".."
+)
+
+func testRegistry() *stationRegistry {
+	r := &stationRegistry{byCode: make(map[string]*pb.Station)}
+	for _, s := range [][2]string{{"Twyford", "TWY"}, {"Reading West", "RDW"}, {"London Paddington", "PAD"}, {"Reading", "RDG"}, {"Maidenhead", "MAI"}} {
+		station := &pb.Station{StationName: s[0], CrsCode: s[1]}
+		r.stations = append(r.stations, station)
+		r.byCode[station.CrsCode] = station
+	}
+	sort.SliceStable(r.stations, func(i, j int) bool { return r.stations[i].StationName < r.stations[j].StationName })
+	return r
+}
+
+func crsCodes(stations []*pb.Station) string {
+	var crs []string
+	for _, station := range stations {
+		crs = append(crs, station.CrsCode)
+	}
+	return fmt.Sprint(crs)
+}
+
+func TestMatchStation(t *testing.T) {
+	r := testRegistry()
+	tests := []struct {
+		crs   string
+		query string
+		want  int
+	}{
+		{"RDG", "rdg", 100},
+		{"RDG", "reading", 95},
+		{"RDW", "reading", 90},
+		{"RDW", "west", 80},
+		{"PAD", "padd", 80},
+		{"PAD", "ddington", 70},
+		// l-n-d-p-a-d skips ahead twice
+		{"PAD", "lndpad", 58},
+		{"RDG", "rdn", 58},
+		// Typos against the whole name or a word in it
+		{"TWY", "twyfrod", 10},
+		{"MAI", "maidenhaed", 10},
+		{"TWY", "twx", 20},
+		{"TWY", "xyz", 0},
+		{"TWY", "", 0},
+		// Too many typos for so short a query
+		{"TWY", "tx", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.crs+" "+tt.query, func(t *testing.T) {
+			station, _ := r.Lookup(tt.crs)
+			if got := matchStation(station, tt.query); got != tt.want {
+				t.Errorf("matchStation(%s, %q) = %d, want %d", station.StationName, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	stations := testRegistry().stations
+	tests := []struct {
+		size     int32
+		token    string
+		want     string
+		wantNext string
+		wantErr  bool
+	}{
+		{2, "", "[PAD MAI]", "2", false},
+		{2, "2", "[RDG RDW]", "4", false},
+		{2, "4", "[TWY]", "", false},
+		{5, "", "[PAD MAI RDG RDW TWY]", "", false},
+		{0, "", "[PAD MAI RDG RDW TWY]", "", false},
+		{-1, "3", "[RDW TWY]", "", false},
+		{2, "5", "[]", "", false},
+		{2, "6", "", "", true},
+		{2, "-1", "", "", true},
+		{2, "next", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d from %q", tt.size, tt.token), func(t *testing.T) {
+			got, next, err := page(stations, tt.size, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (crsCodes(got) != tt.want || next != tt.wantNext) {
+				t.Errorf("got %s next %q, want %s next %q", crsCodes(got), next, tt.want, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	r := testRegistry()
+	var found []*pb.Station
+	token := ""
+	for i := 0; ; i++ {
+		response, err := r.Search(" Rea ", 1, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.TotalSize != 2 {
+			t.Errorf("total size = %d, want 2", response.TotalSize)
+		}
+		found = append(found, response.Stations...)
+		if token = response.NextPageToken; len(token) == 0 || i > 2 {
+			break
+		}
+	}
+	// Both are name prefixes so they stay in name order
+	if got := crsCodes(found); got != "[RDG RDW]" {
+		t.Errorf("search for Rea found %s, want [RDG RDW]", got)
+	}
+	response, err := r.Search("rdw", 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The CRS code beats the name matches
+	if got := crsCodes(response.Stations); got != "[RDW]" {
+		t.Errorf("search for rdw found %s, want [RDW]", got)
+	}
+}
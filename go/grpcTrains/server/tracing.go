@@ -0,0 +1,171 @@
+/*
+ tracing.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+OpenTelemetry tracing for the Trains server.
+A slow board could be slow anywhere so every gRPC call, REST gateway request and
+transportAPI request gets a span, as do the timetable cache lookup and rendering
+of the HTML board.  Spans are named after the RPC, route or endpoint rather than
+the path so that there are only a handful of span names, and carry the station
+codes, train_uid or date they are for as attributes.  The trace context is passed
+on to transportAPI in a traceparent header.
+-trace-exporter picks where spans go: none, stdout for a quick look, or otlp for a
+local collector such as Jaeger.  The OTLP exporter is set up from the standard
+OTEL_EXPORTER_OTLP_* environment variables and sends to localhost:4317 by default.
+
+Installation
+------------
+$ go get go.opentelemetry.io/otel go.opentelemetry.io/otel/sdk
+$ go get go.opentelemetry.io/otel/exporters/stdout/stdouttrace go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc
+$ go get go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc
+$ go get go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp
+$ docker run -p 16686:16686 -p 4317:4317 jaegertracing/all-in-one			# then use -trace-exporter otlp
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "trains/grpcTrains/server"
+
+var tracer = otel.Tracer(tracerName)
+
+// Span attributes for what a request was about
+var (
+	stationCodeKey = attribute.Key("trains.station_code")
+	destCodeKey    = attribute.Key("trains.dest_code")
+	trainUidKey    = attribute.Key("trains.train_uid")
+	dateKey        = attribute.Key("trains.date")
+)
+
+// setupTracing installs the global tracer provider for the given exporter and
+// returns a function that flushes any spans still buffered
+func setupTracing(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		spanExporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected none, stdout or otlp", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name
+	res, err := resource.New(ctx, resource.WithTelemetrySDK(), resource.WithAttributes(semconv.ServiceName("trains")), resource.WithFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Gateway paths that are a route of their own
+var gatewayPaths = map[string]bool{
+	"/v1/trains":            true,
+	"/v1/trains:watch":      true,
+	"/v1/stations":          true,
+	"/v1/stations:search":   true,
+	"/v2/trains":            true,
+	"/board":                true,
+	"/gtfs-rt/trip-updates": true,
+	"/openapi.json":         true,
+}
+
+// Gateway routes that end in a station code or train_uid
+var gatewayTemplates = []struct {
+	prefix string
+	route  string
+	key    attribute.Key
+}{
+	{"/v1/stations/", "/v1/stations/{crs_code}", stationCodeKey},
+	{"/v1/services/", "/v1/services/{train_uid}", trainUidKey},
+	{"/v2/services/", "/v2/services/{train_uid}", trainUidKey},
+}
+
+// gatewayRoute is the route a gateway request is for, with any station code or
+// train_uid in the path or query as attributes
+func gatewayRoute(r *http.Request) (string, []attribute.KeyValue) {
+	var attrs []attribute.KeyValue
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name string
+		key  attribute.Key
+	}{{"from", stationCodeKey}, {"to", destCodeKey}, {"date", dateKey}} {
+		if value := query.Get(param.name); len(value) > 0 {
+			attrs = append(attrs, param.key.String(strings.ToUpper(value)))
+		}
+	}
+	if gatewayPaths[r.URL.Path] {
+		return r.URL.Path, attrs
+	}
+	for _, template := range gatewayTemplates {
+		if code := strings.TrimPrefix(r.URL.Path, template.prefix); len(code) < len(r.URL.Path) && len(code) > 0 {
+			return template.route, append(attrs, template.key.String(code))
+		}
+	}
+	return "other", attrs
+}
+
+// traceHandler gives every REST gateway and board request a span of its own,
+// named after the route, except for Prometheus scrapes
+func traceHandler(h http.Handler) http.Handler {
+	tagged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, attrs := gatewayRoute(r)
+		trace.SpanFromContext(r.Context()).SetAttributes(attrs...)
+		h.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(tagged, "gateway",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			route, _ := gatewayRoute(r)
+			return r.Method + " " + route
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics"
+		}),
+	)
+}
+
+// endSpan records err, if any, on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGatewayRoute(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		attrs  string
+	}{
+		{"/v1/trains?from=oxf&to=PAD", "/v1/trains", "[trains.station_code:OXF trains.dest_code:PAD]"},
+		{"/v1/stations:search?query=reading", "/v1/stations:search", "[]"},
+		{"/v1/stations/PAD", "/v1/stations/{crs_code}", "[trains.station_code:PAD]"},
+		{"/v1/services/C12345?date=2019-10-26", "/v1/services/{train_uid}", "[trains.date:2019-10-26 trains.train_uid:C12345]"},
+		{"/v2/services/C12345", "/v2/services/{train_uid}", "[trains.train_uid:C12345]"},
+		{"/board?from=TWY&to=PAD", "/board", "[trains.station_code:TWY trains.dest_code:PAD]"},
+		// Anything else shares one name however many paths are tried
+		{"/v1/services/", "other", "[]"},
+		{"/wp-login.php", "other", "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			route, attrs := gatewayRoute(httptest.NewRequest("GET", tt.target, nil))
+			var described []string
+			for _, attr := range attrs {
+				described = append(described, string(attr.Key)+":"+attr.Value.Emit())
+			}
+			if route != tt.want || fmt.Sprint(described) != tt.attrs {
+				t.Errorf("gatewayRoute(%s) = %s %v, want %s %s", tt.target, route, described, tt.want, tt.attrs)
+			}
+		})
+	}
+}
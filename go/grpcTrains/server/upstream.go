@@ -0,0 +1,387 @@
+/*
+ upstream.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Server side access to transportAPI for the Trains gRPC service.
+This follows the same flow as trainsClient.go: one call for the live departures
+board and then one call per train for its service timetable.  Unlike the command
+line version, failures are returned as errors rather than ending the process and
+the timetable calls are all made concurrently.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb ".."
+
+	grequests "github.com/levigross/grequests"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var APP_ID = ""
+var APP_KEY = ""
+var UPSTREAM_URL = "http://transportapi.com/v3/uk/train"
+var UPSTREAM_TIMEOUT = 10 * time.Second
+
+var upstreamClient = newUpstreamClient(UPSTREAM_TIMEOUT)
+
+var upstreamBreaker = newCircuitBreaker(breakerFailures, breakerCooldown, nil)
+
+var errMissingCredentials = errors.New("transportAPI credentials are missing")
+
+type TrainStop struct {
+	StationCode           string `json:"station_code"`
+	TiplocCode            string `json:"tiploc_code"`
+	StationName           string `json:"station_name"`
+	StopType              string `json:"stop_type"`
+	Platform              string `json:"platform"`
+	AimedDepartureDate    string `json:"aimed_departure_date"`
+	AimedDeparture        string `json:"aimed_departure_time"`
+	AimedArrivalDate      string `json:"aimed_arrival_date"`
+	AimedArrival          string `json:"aimed_arrival_time"`
+	AimedPassDate         string `json:"aimed_pass_date"`
+	AimedPass             string `json:"aimed_pass_time"`
+	ExpectedDepartureDate string `json:"expected_departure_date"`
+	ExpectedDeparture     string `json:"expected_departure_time"`
+	ExpectedArrivalDate   string `json:"expected_arrival_date"`
+	ExpectedArrival       string `json:"expected_arrival_time"`
+	ExpectedPassDate      string `json:"expected_pass_date"`
+	ExpectedPass          string `json:"expected_pass_time"`
+	Status                string `json:"status"`
+}
+
+type TrainStops struct {
+	Service         string      `json:"service"`
+	TrainUid        string      `json:"train_uid"`
+	Headcode        string      `json:"headcode"`
+	TrainStatus     string      `json:"train_status"`
+	OriginName      string      `json:"origin_name"`
+	DestinationName string      `json:"destination_name"`
+	Date            string      `json:"date"`
+	Category        string      `json:"category"`
+	Operator        string      `json:"operator"`
+	OperatorName    string      `json:"operator_name"`
+	Stops           []TrainStop `json:"stops"`
+}
+
+type TrainTimetable struct {
+	Url string `json:"id"`
+}
+
+type TrainDeparture struct {
+	Mode              string         `json:"mode"`
+	Service           string         `json:"service"`
+	TrainUid          string         `json:"train_uid"`
+	Platform          string         `json:"platform"`
+	Operator          string         `json:"operator"`
+	OperatorName      string         `json:"operator_name"`
+	AimedDeparture    string         `json:"aimed_departure_time"`
+	AimedArrival      string         `json:"aimed_arrival_time"`
+	AimedPass         string         `json:"aimed_pass_time"`
+	OriginName        string         `json:"origin_name"`
+	DestinationName   string         `json:"destination_name"`
+	Source            string         `json:"source"`
+	Category          string         `json:"category"`
+	ServiceTimetable  TrainTimetable `json:"service_timetable"`
+	Status            string         `json:"status"`
+	ExpectedArrival   string         `json:"expected_arrival_time"`
+	ExpectedDeparture string         `json:"expected_departure_time"`
+}
+
+type TrainDepartures struct {
+	All []TrainDeparture `json:"all"`
+}
+
+type TrainJourney struct {
+	Date        string          `json:"date"`
+	TimeOfDay   string          `json:"time_of_day"`
+	RequestTime string          `json:"request_time"`
+	StationName string          `json:"station_name"`
+	StationCode string          `json:"station_code"`
+	Departures  TrainDepartures `json:"departures"`
+}
+
+type trainStopsResult struct {
+	index int
+	stops *TrainStops
+	err   error
+}
+
+// ExistsFile check whether the file exists
+func ExistsFile(filename string) bool {
+	if _, err := os.Stat(filename); err != nil {
+		if os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+func readCred(envvar string, fname string) (string, error) {
+	// First we check if corresponding environment variable exists, then for a local file.
+	value := os.Getenv(envvar)
+	if len(value) > 0 {
+		return value, nil
+	}
+	if ExistsFile(fname) {
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("could not find any cred for %s", fname)
+}
+
+// upstreamGet makes a transportAPI request through the circuit breaker, traced
+// with a span carrying the given attributes
+func upstreamGet(ctx context.Context, url string, params map[string]string, attrs ...attribute.KeyValue) (resp *grequests.Response, err error) {
+	path, _, _ := strings.Cut(url, "?")
+	ctx, span := tracer.Start(ctx, "transportAPI "+upstreamEndpoint(path), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	span.SetAttributes(attribute.String("http.request.method", "GET"), attribute.String("url.full", redactURL(url)))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if !resp.Ok {
+				span.SetStatus(codes.Error, resp.RawResponse.Status)
+			}
+		}
+		endSpan(span, err)
+	}()
+	if len(APP_ID) == 0 || len(APP_KEY) == 0 {
+		return nil, errMissingCredentials
+	}
+	if err := upstreamBreaker.Allow(); err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"X-Request-Id": requestID(ctx)}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	start := time.Now()
+	resp, err = grequests.Get(url, &grequests.RequestOptions{Params: params, Headers: headers, Context: ctx, HTTPClient: upstreamClient})
+	// The error text holds the full URL, credentials and all
+	err = redactError(err)
+	// Callers giving up is not a sign that transportAPI is in trouble
+	if err != nil && ctx.Err() != nil {
+		upstreamBreaker.Release()
+		return nil, err
+	}
+	upstreamBreaker.Record(err != nil || resp.StatusCode >= 500)
+	if err != nil {
+		slog.WarnContext(ctx, "transportAPI request failed", "url", url, "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	slog.DebugContext(ctx, "transportAPI request", "url", resp.RawResponse.Request.URL.String(), "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
+
+func getTrainsCallingAt(ctx context.Context, station_code string, dest_code string) (*TrainJourney, error) {
+	url := fmt.Sprintf("%s/station/%s/live.json", UPSTREAM_URL, station_code)
+	params := make(map[string]string)
+	params["app_id"] = APP_ID
+	params["app_key"] = APP_KEY
+	params["station_code"] = station_code
+	// With no destination the board lists every departure from the station
+	if len(dest_code) > 0 {
+		params["calling_at"] = dest_code
+	}
+	params["type"] = "departure"
+
+	resp, err := upstreamGet(ctx, url, params, stationCodeKey.String(station_code), destCodeKey.String(dest_code))
+	if err != nil {
+		return nil, fmt.Errorf("unable to make journey request: %v", err)
+	}
+	if !resp.Ok {
+		return nil, fmt.Errorf("journey request failed with status %d", resp.StatusCode)
+	}
+	journey := &TrainJourney{}
+	if err := resp.JSON(journey); err != nil {
+		return nil, fmt.Errorf("cannot deserialize journey JSON: %v", err)
+	}
+	return journey, nil
+}
+
+func getTrainStops(ctx context.Context, timetable_url string, train_uid string) (*TrainStops, error) {
+	// service_timetable links from the departures board already carry the credentials
+	return requestTrainStops(ctx, timetable_url, nil, trainUidKey.String(train_uid))
+}
+
+// getServiceTimetable fetches the calling pattern for one train on a given date
+func getServiceTimetable(ctx context.Context, train_uid string, date string) (*TrainStops, error) {
+	url := fmt.Sprintf("%s/service/train_uid:%s/%s/timetable.json", UPSTREAM_URL, train_uid, date)
+	params := make(map[string]string)
+	params["app_id"] = APP_ID
+	params["app_key"] = APP_KEY
+	params["live"] = "true"
+	return requestTrainStops(ctx, url, params, trainUidKey.String(train_uid), dateKey.String(date))
+}
+
+func requestTrainStops(ctx context.Context, timetable_url string, params map[string]string, attrs ...attribute.KeyValue) (*TrainStops, error) {
+	resp, err := upstreamGet(ctx, timetable_url, params, attrs...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make stops request: %v", err)
+	}
+	if !resp.Ok {
+		return nil, fmt.Errorf("stops request failed with status %d", resp.StatusCode)
+	}
+	stops := &TrainStops{}
+	if err := resp.JSON(stops); err != nil {
+		return nil, fmt.Errorf("cannot deserialize stops JSON: %v", err)
+	}
+	return stops, nil
+}
+
+func StopProducer(ctx context.Context, timetable_url string, train_uid string, index int, ch chan<- trainStopsResult) {
+	stops, err := getTrainStops(ctx, timetable_url, train_uid)
+	ch <- trainStopsResult{index: index, stops: stops, err: err}
+}
+
+// knownStops are the stops fetched for each train along with the departure they
+// were fetched for, so that a poller can reuse them while the departure is unchanged
+type knownStops map[string]knownTrain
+
+type knownTrain struct {
+	departure TrainDeparture
+	stops     []TrainStop
+}
+
+// rememberStops notes the stops fetched for the trains on a board
+func rememberStops(journey *TrainJourney, stops map[string][]TrainStop) knownStops {
+	known := make(knownStops)
+	for _, train := range journey.Departures.All {
+		if trainStops, ok := stops[train.TrainUid]; ok {
+			known[train.TrainUid] = knownTrain{departure: train, stops: trainStops}
+		}
+	}
+	return known
+}
+
+// getAllTrainStops fetches the timetable for every departure concurrently.  Stops
+// already known from an earlier call are reused as long as nothing about the
+// departure has changed since, as the live expected times at the other stops
+// will only have moved if the board has.  A timetable that can't be fetched is
+// logged and leaves that train without stops rather than failing the board.
+func getAllTrainStops(ctx context.Context, journey *TrainJourney, known knownStops) map[string][]TrainStop {
+	departures := journey.Departures.All
+	all := make(map[string][]TrainStop)
+	var missing []int
+	_, span := tracer.Start(ctx, "timetable cache lookup", trace.WithAttributes(stationCodeKey.String(journey.StationCode)))
+	for i, train := range departures {
+		if k, ok := known[train.TrainUid]; ok && k.departure == train {
+			timetableCache.WithLabelValues("hit").Inc()
+			all[train.TrainUid] = k.stops
+			continue
+		}
+		timetableCache.WithLabelValues("miss").Inc()
+		missing = append(missing, i)
+	}
+	span.SetAttributes(attribute.Int("trains.cache.hits", len(all)), attribute.Int("trains.cache.misses", len(missing)))
+	span.End()
+	ch := make(chan trainStopsResult, len(missing))
+	for _, i := range missing {
+		go StopProducer(ctx, departures[i].ServiceTimetable.Url, departures[i].TrainUid, i, ch)
+	}
+	pending := len(missing)
+	timetableFanout.Observe(float64(pending))
+	for ; pending > 0; pending-- {
+		result := <-ch
+		if result.err != nil {
+			slog.WarnContext(ctx, "Cannot fetch timetable, leaving out its stops", "train_uid", departures[result.index].TrainUid, "error", result.err)
+			continue
+		}
+		all[departures[result.index].TrainUid] = result.stops.Stops
+	}
+	return all
+}
+
+func parsePlatform(platform string) int32 {
+	// trains.proto carries platforms as numbers so "4A" becomes 4
+	digits := strings.TrimRightFunc(platform, func(r rune) bool { return r < '0' || r > '9' })
+	n, _ := strconv.Atoi(digits)
+	return int32(n)
+}
+
+func newTrainResponse(journey *TrainJourney, dest_code string, stops map[string][]TrainStop) *pb.TrainResponse {
+	response := &pb.TrainResponse{
+		StationCode: journey.StationCode,
+		StationName: journey.StationName,
+		DestCode:    dest_code,
+		Date:        journey.Date,
+		TimeOfDay:   journey.TimeOfDay,
+	}
+	for _, train := range journey.Departures.All {
+		departure := &pb.TrainResponse_TrainDeparture{
+			Mode:                  train.Mode,
+			Service:               train.Service,
+			TrainUid:              train.TrainUid,
+			Platform:              parsePlatform(train.Platform),
+			Operator:              train.Operator,
+			OperatorName:          train.OperatorName,
+			OriginName:            train.OriginName,
+			DestinationName:       train.DestinationName,
+			Source:                train.Source,
+			Status:                train.Status,
+			ExpectedArrivalTime:   train.ExpectedArrival,
+			ExpectedDepartureTime: train.ExpectedDeparture,
+		}
+		for _, stop := range stops[train.TrainUid] {
+			if stop.StationCode == dest_code && len(response.DestName) == 0 {
+				response.DestName = stop.StationName
+			}
+			departure.Stops = append(departure.Stops, &pb.TrainResponse_TrainStop{
+				StationCode:     stop.StationCode,
+				StationName:     stop.StationName,
+				Platform:        parsePlatform(stop.Platform),
+				ExpectedArrival: stop.ExpectedArrival,
+			})
+		}
+		response.Departures = append(response.Departures, departure)
+	}
+	return response
+}
+
+// fetchJourney gets the board from station_code to dest_code along with the stops for
+// every train on it, reusing any known stops
+func fetchJourney(ctx context.Context, station_code string, dest_code string, known knownStops) (*TrainJourney, map[string][]TrainStop, error) {
+	journey, err := getTrainsCallingAt(ctx, station_code, dest_code)
+	if err != nil {
+		return nil, nil, err
+	}
+	return journey, getAllTrainStops(ctx, journey, known), nil
+}
+
+// fetchTrains builds a full board from station_code to dest_code, reusing any known
+// stops and returning the stops known now
+func fetchTrains(ctx context.Context, station_code string, dest_code string, known knownStops) (*pb.TrainResponse, knownStops, error) {
+	journey, stops, err := fetchJourney(ctx, station_code, dest_code, known)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newTrainResponse(journey, dest_code, stops), rememberStops(journey, stops), nil
+}
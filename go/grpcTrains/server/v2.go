@@ -0,0 +1,246 @@
+/*
+ v2.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Translation of transportAPI data into the version 2 messages in trains.proto.
+transportAPI gives times as "HH:MM" strings local to Great Britain, with a separate
+date on timetable stops but only the board date for departures.  Departures that
+are more than twelve hours before the board time are taken to be after midnight,
+and a stop without a date is put on whichever day is nearest the train's departure.
+Statuses such as "ON TIME" and stop types such as "LO" become enums and delays are
+worked out from the expected and aimed times.  The version 1 messages are built
+separately in upstream.go and are not affected.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	pb ".."
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+const CLOCK_FORMAT = "2006-01-02 15:04"
+
+var london = loadLondon()
+
+func loadLondon() *time.Location {
+	location, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		log.Printf("Cannot load Europe/London time zone, using local time: %v", err)
+		return time.Local
+	}
+	return location
+}
+
+// localTime combines a YYYY-MM-DD date and an "HH:MM" time in Great Britain
+func localTime(date string, clock string) (time.Time, bool) {
+	if len(date) == 0 || len(clock) == 0 {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(CLOCK_FORMAT, date+" "+clock, london)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// boardTime places a departure time on the right day relative to the board time
+func boardTime(journey *TrainJourney, clock string) (time.Time, bool) {
+	t, ok := localTime(journey.Date, clock)
+	if !ok {
+		return t, false
+	}
+	if board, ok := localTime(journey.Date, journey.TimeOfDay); ok && board.Sub(t) > 12*time.Hour {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// stopTime uses the date given for the stop, falling back to the day that puts
+// clock within twelve hours of the train's departure
+func stopTime(date string, clock string, departure time.Time) (time.Time, bool) {
+	if len(date) > 0 {
+		return localTime(date, clock)
+	}
+	if departure.IsZero() {
+		return time.Time{}, false
+	}
+	t, ok := localTime(departure.Format(DATE_FORMAT), clock)
+	switch {
+	case !ok:
+	case t.Sub(departure) > 12*time.Hour:
+		t = t.AddDate(0, 0, -1)
+	case departure.Sub(t) > 12*time.Hour:
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, ok
+}
+
+// serviceDeparture is when a service leaves its first stop, or midday on its date
+// if that isn't known
+func serviceDeparture(service *TrainStops) time.Time {
+	if len(service.Stops) > 0 {
+		first := service.Stops[0]
+		date := first.AimedDepartureDate
+		if len(date) == 0 {
+			date = service.Date
+		}
+		if t, ok := localTime(date, first.AimedDeparture); ok {
+			return t
+		}
+	}
+	t, _ := localTime(service.Date, "12:00")
+	return t
+}
+
+func timestampProto(t time.Time, ok bool) *timestamp.Timestamp {
+	if !ok {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// delayProto is expected less aimed, or nil if either is missing
+func delayProto(aimed *timestamp.Timestamp, expected *timestamp.Timestamp) *duration.Duration {
+	if aimed == nil || expected == nil {
+		return nil
+	}
+	a, _ := ptypes.Timestamp(aimed)
+	e, _ := ptypes.Timestamp(expected)
+	return ptypes.DurationProto(e.Sub(a))
+}
+
+func parseStatus(status string) pb.TrainStatus {
+	name := "TRAIN_STATUS_" + strings.Replace(strings.ToUpper(strings.TrimSpace(status)), " ", "_", -1)
+	if value, ok := pb.TrainStatus_value[name]; ok {
+		return pb.TrainStatus(value)
+	}
+	return pb.TrainStatus_TRAIN_STATUS_UNKNOWN
+}
+
+func parseStopType(stop TrainStop) pb.StopType {
+	if len(stop.AimedPass) > 0 && len(stop.AimedArrival) == 0 && len(stop.AimedDeparture) == 0 {
+		return pb.StopType_STOP_TYPE_PASS
+	}
+	switch stop.StopType {
+	case "LO":
+		return pb.StopType_STOP_TYPE_ORIGIN
+	case "LI":
+		return pb.StopType_STOP_TYPE_INTERMEDIATE
+	case "LT":
+		return pb.StopType_STOP_TYPE_DESTINATION
+	}
+	return pb.StopType_STOP_TYPE_UNKNOWN
+}
+
+func newTrainStopV2(stop TrainStop, departure time.Time) *pb.TrainStopV2 {
+	v2 := &pb.TrainStopV2{
+		StationCode:       stop.StationCode,
+		TiplocCode:        stop.TiplocCode,
+		StationName:       stop.StationName,
+		StopType:          parseStopType(stop),
+		Platform:          stop.Platform,
+		AimedArrival:      timestampProto(stopTime(stop.AimedArrivalDate, stop.AimedArrival, departure)),
+		AimedDeparture:    timestampProto(stopTime(stop.AimedDepartureDate, stop.AimedDeparture, departure)),
+		AimedPass:         timestampProto(stopTime(stop.AimedPassDate, stop.AimedPass, departure)),
+		ExpectedArrival:   timestampProto(stopTime(stop.ExpectedArrivalDate, stop.ExpectedArrival, departure)),
+		ExpectedDeparture: timestampProto(stopTime(stop.ExpectedDepartureDate, stop.ExpectedDeparture, departure)),
+		ExpectedPass:      timestampProto(stopTime(stop.ExpectedPassDate, stop.ExpectedPass, departure)),
+		Status:            parseStatus(stop.Status),
+	}
+	// Prefer the departure delay, then arrival, then pass for the delay at this stop
+	switch {
+	case v2.ExpectedDeparture != nil && v2.AimedDeparture != nil:
+		v2.Delay = delayProto(v2.AimedDeparture, v2.ExpectedDeparture)
+	case v2.ExpectedArrival != nil && v2.AimedArrival != nil:
+		v2.Delay = delayProto(v2.AimedArrival, v2.ExpectedArrival)
+	default:
+		v2.Delay = delayProto(v2.AimedPass, v2.ExpectedPass)
+	}
+	return v2
+}
+
+func newTrainResponseV2(journey *TrainJourney, dest_code string, dest_name string, stops map[string][]TrainStop) *pb.TrainResponseV2 {
+	response := &pb.TrainResponseV2{
+		StationCode: journey.StationCode,
+		StationName: journey.StationName,
+		DestCode:    dest_code,
+		DestName:    dest_name,
+	}
+	if requestTime, err := time.Parse(time.RFC3339, journey.RequestTime); err == nil {
+		response.RequestTime = timestampProto(requestTime, true)
+	} else {
+		response.RequestTime = timestampProto(localTime(journey.Date, journey.TimeOfDay))
+	}
+	for _, train := range journey.Departures.All {
+		departure := &pb.TrainDepartureV2{
+			Mode:              train.Mode,
+			Service:           train.Service,
+			TrainUid:          train.TrainUid,
+			Platform:          train.Platform,
+			Operator:          train.Operator,
+			OperatorName:      train.OperatorName,
+			OriginName:        train.OriginName,
+			DestinationName:   train.DestinationName,
+			Source:            train.Source,
+			Category:          train.Category,
+			Status:            parseStatus(train.Status),
+			AimedDeparture:    timestampProto(boardTime(journey, train.AimedDeparture)),
+			ExpectedDeparture: timestampProto(boardTime(journey, train.ExpectedDeparture)),
+		}
+		departure.Delay = delayProto(departure.AimedDeparture, departure.ExpectedDeparture)
+		aimed, ok := boardTime(journey, train.AimedDeparture)
+		if !ok {
+			aimed, _ = localTime(journey.Date, journey.TimeOfDay)
+		}
+		for _, stop := range stops[train.TrainUid] {
+			departure.Stops = append(departure.Stops, newTrainStopV2(stop, aimed))
+		}
+		response.Departures = append(response.Departures, departure)
+	}
+	return response
+}
+
+func newServiceResponseV2(service *TrainStops) *pb.ServiceResponseV2 {
+	response := &pb.ServiceResponseV2{
+		TrainUid:        service.TrainUid,
+		Service:         service.Service,
+		Headcode:        service.Headcode,
+		TrainStatus:     parseStatus(service.TrainStatus),
+		Operator:        service.Operator,
+		OperatorName:    service.OperatorName,
+		OriginName:      service.OriginName,
+		DestinationName: service.DestinationName,
+		Date:            service.Date,
+		Category:        service.Category,
+	}
+	departure := serviceDeparture(service)
+	for _, stop := range service.Stops {
+		response.Stops = append(response.Stops, newTrainStopV2(stop, departure))
+	}
+	return response
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+func TestNewTrainResponseV2AcrossMidnight(t *testing.T) {
+	journey := &TrainJourney{StationCode: "RDG", Date: "2019-10-26", TimeOfDay: "23:50"}
+	journey.Departures.All = []TrainDeparture{
+		{TrainUid: "C1", AimedDeparture: "23:55", ExpectedDeparture: "00:02"},
+		{TrainUid: "C2", AimedDeparture: "00:10"},
+	}
+	stops := map[string][]TrainStop{
+		"C1": {
+			{StationCode: "OXF", AimedDeparture: "23:30"},
+			{StationCode: "RDG", AimedArrival: "23:53", AimedDeparture: "23:55", ExpectedDeparture: "00:02"},
+			{StationCode: "PAD", AimedArrival: "00:25", ExpectedArrival: "00:31"},
+		},
+		"C2": {
+			{StationCode: "OXF", AimedDepartureDate: "2019-10-26", AimedDeparture: "23:45"},
+			{StationCode: "RDG", AimedDepartureDate: "2019-10-27", AimedDeparture: "00:10"},
+			{StationCode: "PAD", AimedArrival: "00:40"},
+		},
+	}
+	response := newTrainResponseV2(journey, "PAD", "London Paddington", stops)
+	c1, c2 := response.Departures[0], response.Departures[1]
+	tests := []struct {
+		name string
+		ts   *timestamp.Timestamp
+		want string
+	}{
+		{"C1 aimed departure", c1.AimedDeparture, "2019-10-26 23:55"},
+		{"C1 expected departure", c1.ExpectedDeparture, "2019-10-27 00:02"},
+		{"C2 aimed departure", c2.AimedDeparture, "2019-10-27 00:10"},
+		{"C1 origin before the board", c1.Stops[0].AimedDeparture, "2019-10-26 23:30"},
+		{"C1 expected at this station", c1.Stops[1].ExpectedDeparture, "2019-10-27 00:02"},
+		{"C1 arrival after midnight", c1.Stops[2].AimedArrival, "2019-10-27 00:25"},
+		{"C1 expected arrival after midnight", c1.Stops[2].ExpectedArrival, "2019-10-27 00:31"},
+		{"C2 origin dated yesterday", c2.Stops[0].AimedDeparture, "2019-10-26 23:45"},
+		{"C2 arrival after midnight", c2.Stops[2].AimedArrival, "2019-10-27 00:40"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ptypes.Timestamp(tt.ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.In(london).Format(CLOCK_FORMAT) != tt.want {
+				t.Errorf("got %s, want %s", got.In(london).Format(CLOCK_FORMAT), tt.want)
+			}
+		})
+	}
+	if got := c1.Delay.GetSeconds(); got != int64(7*time.Minute/time.Second) {
+		t.Errorf("C1 delay = %ds, want 420s", got)
+	}
+}
+
+func TestNewServiceResponseV2AcrossMidnight(t *testing.T) {
+	service := &TrainStops{TrainUid: "C1", Date: "2019-10-26", Stops: []TrainStop{
+		{StationCode: "OXF", AimedDeparture: "23:40"},
+		{StationCode: "RDG", AimedArrival: "23:58", AimedDeparture: "00:01"},
+		{StationCode: "PAD", AimedArrivalDate: "2019-10-27", AimedArrival: "00:30"},
+	}}
+	response := newServiceResponseV2(service)
+	tests := []struct {
+		ts   *timestamp.Timestamp
+		want string
+	}{
+		{response.Stops[0].AimedDeparture, "2019-10-26 23:40"},
+		{response.Stops[1].AimedArrival, "2019-10-26 23:58"},
+		{response.Stops[1].AimedDeparture, "2019-10-27 00:01"},
+		{response.Stops[2].AimedArrival, "2019-10-27 00:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := ptypes.Timestamp(tt.ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.In(london).Format(CLOCK_FORMAT) != tt.want {
+				t.Errorf("got %s, want %s", got.In(london).Format(CLOCK_FORMAT), tt.want)
+			}
+		})
+	}
+}
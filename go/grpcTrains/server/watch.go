@@ -0,0 +1,259 @@
+/*
+ watch.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Shared upstream polling behind the WatchDepartures streaming RPC.
+Every from/to route being watched has a single poller no matter how many clients
+are subscribed to it, so many subscribers cost one transportAPI poll per interval.
+Each poll is compared with the previous board to work out incremental updates:
+new services, status or estimate changes, platform changes and departures.
+Platforms are compared as transportAPI gives them, so a move from 4A to 4B is a
+platform change even though trains.proto only carries the number.
+The stops for each train are kept between polls and fetched again whenever the
+train's entry on the board changes.
+The poller is stopped once the last subscriber for its route goes away.
+A route with no destination polls the whole departures board for a station
+without fetching the stops for each train, which is all the GTFS-Realtime feed
+needs, and the feed shares the poller with anyone else watching that board.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	pb ".."
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	pollInterval     = 60 * time.Second
+	subscriberBuffer = 32
+)
+
+type subscriber struct {
+	ch     chan *pb.DepartureUpdate
+	primed bool
+}
+
+type routeWatch struct {
+	from        string
+	to          string
+	board       *pb.TrainResponse
+	journey     *TrainJourney
+	fetched     time.Time
+	stops       knownStops
+	subscribers map[*subscriber]bool
+	cancel      context.CancelFunc
+}
+
+// departureWatcher multiplexes subscribers onto one poller per route
+type departureWatcher struct {
+	mu       sync.Mutex
+	routes   map[string]*routeWatch
+	interval time.Duration
+}
+
+func newDepartureWatcher(interval time.Duration) *departureWatcher {
+	return &departureWatcher{routes: make(map[string]*routeWatch), interval: interval}
+}
+
+// Subscribe registers for updates on a route.  The returned channel is closed if the
+// subscriber falls too far behind; call the returned function to unsubscribe.
+func (w *departureWatcher) Subscribe(from string, to string) (<-chan *pb.DepartureUpdate, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := from + "-" + to
+	route, ok := w.routes[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		route = &routeWatch{from: from, to: to, subscribers: make(map[*subscriber]bool), cancel: cancel}
+		w.routes[key] = route
+		go w.poll(ctx, key, route)
+	}
+	sub := &subscriber{ch: make(chan *pb.DepartureUpdate, subscriberBuffer)}
+	if route.board != nil {
+		sub.ch <- &pb.DepartureUpdate{Type: pb.DepartureUpdate_BOARD, Board: route.board}
+		sub.primed = true
+	}
+	route.subscribers[sub] = true
+	return sub.ch, func() { w.unsubscribe(key, route, sub) }
+}
+
+// Latest returns the last board fetched for a route being watched and when it was fetched
+func (w *departureWatcher) Latest(from string, to string) (*TrainJourney, time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	route, ok := w.routes[from+"-"+to]
+	if !ok || route.journey == nil {
+		return nil, time.Time{}, false
+	}
+	return route.journey, route.fetched, true
+}
+
+func (w *departureWatcher) unsubscribe(key string, route *routeWatch, sub *subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := route.subscribers[sub]; !ok {
+		return
+	}
+	delete(route.subscribers, sub)
+	close(sub.ch)
+	w.release(key, route)
+}
+
+// release must be called with w.mu held.  It stops polling a route nobody is watching.
+func (w *departureWatcher) release(key string, route *routeWatch) {
+	if len(route.subscribers) > 0 {
+		return
+	}
+	route.cancel()
+	if w.routes[key] == route {
+		delete(w.routes, key)
+	}
+}
+
+func (w *departureWatcher) poll(ctx context.Context, key string, route *routeWatch) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.refresh(ctx, key, route)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *departureWatcher) refresh(ctx context.Context, key string, route *routeWatch) {
+	// route.stops is only touched by this poller goroutine
+	ctx = withRequestID(ctx, "")
+	ctx, span := tracer.Start(ctx, "poll route", trace.WithAttributes(stationCodeKey.String(route.from), destCodeKey.String(route.to)))
+	journey, err := getTrainsCallingAt(ctx, route.from, route.to)
+	var stops map[string][]TrainStop
+	if err == nil && len(route.to) > 0 {
+		stops = getAllTrainStops(ctx, journey, route.stops)
+	}
+	endSpan(span, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "Polling failed", "route", key, "error", err)
+		}
+		return
+	}
+	route.stops = rememberStops(journey, stops)
+	board := newTrainResponse(journey, route.to, stops)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var updates []*pb.DepartureUpdate
+	if route.board != nil {
+		updates = diffBoards(route.board, board, platforms(route.journey), platforms(journey))
+	}
+	route.board = board
+	route.journey = journey
+	route.fetched = time.Now()
+	for sub := range route.subscribers {
+		if !sub.primed {
+			w.send(key, route, sub, &pb.DepartureUpdate{Type: pb.DepartureUpdate_BOARD, Board: board})
+			sub.primed = true
+			continue
+		}
+		for _, update := range updates {
+			if !w.send(key, route, sub, update) {
+				break
+			}
+		}
+	}
+}
+
+// send must be called with w.mu held.  Slow subscribers are dropped rather than
+// holding up everyone else watching the same route.
+func (w *departureWatcher) send(key string, route *routeWatch, sub *subscriber, update *pb.DepartureUpdate) bool {
+	select {
+	case sub.ch <- update:
+		return true
+	default:
+		slog.Warn("Dropping slow subscriber", "route", key)
+		delete(route.subscribers, sub)
+		close(sub.ch)
+		w.release(key, route)
+		return false
+	}
+}
+
+// platforms is the platform of each train on a board as transportAPI gives it
+func platforms(journey *TrainJourney) map[string]string {
+	found := make(map[string]string)
+	if journey == nil {
+		return found
+	}
+	for _, departure := range journey.Departures.All {
+		found[departure.TrainUid] = departure.Platform
+	}
+	return found
+}
+
+// diffBoards works out what has changed between two polls of the same route.
+// Platforms are compared as given by transportAPI where they are known.
+func diffBoards(previous *pb.TrainResponse, current *pb.TrainResponse, previousPlatforms map[string]string, currentPlatforms map[string]string) []*pb.DepartureUpdate {
+	var updates []*pb.DepartureUpdate
+	seen := make(map[string]*pb.TrainResponse_TrainDeparture)
+	for _, departure := range previous.Departures {
+		seen[departure.TrainUid] = departure
+	}
+	for _, departure := range current.Departures {
+		before, ok := seen[departure.TrainUid]
+		if !ok {
+			updates = append(updates, &pb.DepartureUpdate{Type: pb.DepartureUpdate_NEW_SERVICE, Departure: departure})
+			continue
+		}
+		delete(seen, departure.TrainUid)
+		if before.Status != departure.Status || before.ExpectedDepartureTime != departure.ExpectedDepartureTime {
+			updates = append(updates, &pb.DepartureUpdate{
+				Type:                          pb.DepartureUpdate_STATUS_CHANGE,
+				Departure:                     departure,
+				PreviousStatus:                before.Status,
+				PreviousExpectedDepartureTime: before.ExpectedDepartureTime,
+			})
+		}
+		beforePlatform, ok := previousPlatforms[departure.TrainUid]
+		platform, known := currentPlatforms[departure.TrainUid]
+		moved := before.Platform != departure.Platform
+		if ok && known {
+			moved = beforePlatform != platform
+		}
+		if moved {
+			updates = append(updates, &pb.DepartureUpdate{
+				Type:             pb.DepartureUpdate_PLATFORM_CHANGE,
+				Departure:        departure,
+				PreviousPlatform: before.Platform,
+			})
+		}
+	}
+	// Anything left from the previous board has gone from the live departures
+	for _, departure := range previous.Departures {
+		if _, ok := seen[departure.TrainUid]; ok {
+			updates = append(updates, &pb.DepartureUpdate{Type: pb.DepartureUpdate_DEPARTED, Departure: departure})
+		}
+	}
+	return updates
+}
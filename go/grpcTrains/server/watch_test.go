@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pb ".."
+)
+
+// fakeTimetables serves a timetable for every train_uid in the URL path, failing
+// those listed in broken, and counts the requests for each
+type fakeTimetables struct {
+	mu       sync.Mutex
+	requests map[string]int
+	broken   map[string]bool
+}
+
+func (f *fakeTimetables) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	uid := strings.TrimPrefix(r.URL.Path, "/service/")
+	f.mu.Lock()
+	f.requests[uid]++
+	f.mu.Unlock()
+	if f.broken[uid] {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	fmt.Fprintf(w, `{"train_uid": %q, "stops": [{"station_code": "PAD", "expected_arrival_time": "10:%02d"}]}`, uid, len(uid))
+}
+
+func withFakeUpstream(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	oldId, oldKey := APP_ID, APP_KEY
+	APP_ID, APP_KEY = "id", "key"
+	t.Cleanup(func() {
+		ts.Close()
+		APP_ID, APP_KEY = oldId, oldKey
+	})
+	return ts
+}
+
+func testJourney(url string, departures ...TrainDeparture) *TrainJourney {
+	journey := &TrainJourney{StationCode: "TWY", Date: "2019-10-26", TimeOfDay: "10:00"}
+	for _, departure := range departures {
+		departure.ServiceTimetable.Url = url + "/service/" + departure.TrainUid
+		journey.Departures.All = append(journey.Departures.All, departure)
+	}
+	return journey
+}
+
+func TestGetAllTrainStopsRefetchesChangedTrains(t *testing.T) {
+	timetables := &fakeTimetables{requests: make(map[string]int)}
+	ts := withFakeUpstream(t, timetables)
+	ctx := context.Background()
+
+	first := testJourney(ts.URL,
+		TrainDeparture{TrainUid: "A1", Status: "ON TIME", ExpectedDeparture: "10:01"},
+		TrainDeparture{TrainUid: "B2", Status: "ON TIME", ExpectedDeparture: "10:05"})
+	stops := getAllTrainStops(ctx, first, nil)
+	known := rememberStops(first, stops)
+
+	second := testJourney(ts.URL,
+		TrainDeparture{TrainUid: "A1", Status: "ON TIME", ExpectedDeparture: "10:01"},
+		TrainDeparture{TrainUid: "B2", Status: "LATE", ExpectedDeparture: "10:15"})
+	stops = getAllTrainStops(ctx, second, known)
+
+	tests := []struct {
+		uid  string
+		want int
+	}{
+		{"A1", 1},
+		{"B2", 2},
+	}
+	for _, tt := range tests {
+		if got := timetables.requests[tt.uid]; got != tt.want {
+			t.Errorf("%s fetched %d times, want %d", tt.uid, got, tt.want)
+		}
+		if len(stops[tt.uid]) != 1 {
+			t.Errorf("%s has %d stops, want 1", tt.uid, len(stops[tt.uid]))
+		}
+	}
+}
+
+func TestGetAllTrainStopsSurvivesFailedTimetable(t *testing.T) {
+	timetables := &fakeTimetables{requests: make(map[string]int), broken: map[string]bool{"B2": true}}
+	ts := withFakeUpstream(t, timetables)
+	journey := testJourney(ts.URL, TrainDeparture{TrainUid: "A1"}, TrainDeparture{TrainUid: "B2"})
+
+	stops := getAllTrainStops(context.Background(), journey, nil)
+	if len(stops["A1"]) != 1 {
+		t.Errorf("A1 has %d stops, want 1", len(stops["A1"]))
+	}
+	if _, ok := stops["B2"]; ok {
+		t.Errorf("B2 has stops though its timetable failed")
+	}
+	if _, ok := rememberStops(journey, stops)["B2"]; ok {
+		t.Errorf("B2 remembered though its timetable failed, it would never be fetched again")
+	}
+}
+
+func TestDiffBoards(t *testing.T) {
+	previous := &pb.TrainResponse{Departures: []*pb.TrainResponse_TrainDeparture{
+		{TrainUid: "A1", Status: "ON TIME", ExpectedDepartureTime: "10:01", Platform: 1},
+		{TrainUid: "B2", Status: "ON TIME", ExpectedDepartureTime: "10:05", Platform: 2},
+		{TrainUid: "C3", Status: "ON TIME", ExpectedDepartureTime: "10:09", Platform: 3},
+		{TrainUid: "E5", Status: "ON TIME", ExpectedDepartureTime: "10:30", Platform: 4},
+		{TrainUid: "F6", Status: "ON TIME", ExpectedDepartureTime: "10:35", Platform: 0},
+	}}
+	current := &pb.TrainResponse{Departures: []*pb.TrainResponse_TrainDeparture{
+		{TrainUid: "B2", Status: "LATE", ExpectedDepartureTime: "10:10", Platform: 2},
+		{TrainUid: "C3", Status: "ON TIME", ExpectedDepartureTime: "10:09", Platform: 4},
+		{TrainUid: "D4", Status: "ON TIME", ExpectedDepartureTime: "10:20", Platform: 1},
+		{TrainUid: "E5", Status: "ON TIME", ExpectedDepartureTime: "10:30", Platform: 4},
+		{TrainUid: "F6", Status: "ON TIME", ExpectedDepartureTime: "10:35", Platform: 0},
+	}}
+	// Lettered platforms are all the same number in trains.proto
+	previousPlatforms := map[string]string{"A1": "1", "B2": "2", "C3": "3", "E5": "4A", "F6": "B"}
+	currentPlatforms := map[string]string{"B2": "2", "C3": "4", "D4": "1", "E5": "4B", "F6": "C"}
+	want := map[string]pb.DepartureUpdate_UpdateType{
+		"A1": pb.DepartureUpdate_DEPARTED,
+		"B2": pb.DepartureUpdate_STATUS_CHANGE,
+		"C3": pb.DepartureUpdate_PLATFORM_CHANGE,
+		"D4": pb.DepartureUpdate_NEW_SERVICE,
+		"E5": pb.DepartureUpdate_PLATFORM_CHANGE,
+		"F6": pb.DepartureUpdate_PLATFORM_CHANGE,
+	}
+	updates := diffBoards(previous, current, previousPlatforms, currentPlatforms)
+	if len(updates) != len(want) {
+		t.Fatalf("got %d updates, want %d: %v", len(updates), len(want), updates)
+	}
+	for _, update := range updates {
+		if want[update.Departure.TrainUid] != update.Type {
+			t.Errorf("%s: got %v, want %v", update.Departure.TrainUid, update.Type, want[update.Departure.TrainUid])
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	_ "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	grpc "google.golang.org/grpc"
@@ -26,6 +28,132 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// Version 2 messages.  Times are google.protobuf.Timestamps in place of "HH:MM" strings,
+// delays are google.protobuf.Durations, platforms are strings so "4A" survives and
+// statuses and stop types are enums.  The version 1 messages above are unchanged.
+type TrainStatus int32
+
+const (
+	TrainStatus_TRAIN_STATUS_UNKNOWN          TrainStatus = 0
+	TrainStatus_TRAIN_STATUS_ON_TIME          TrainStatus = 1
+	TrainStatus_TRAIN_STATUS_EARLY            TrainStatus = 2
+	TrainStatus_TRAIN_STATUS_LATE             TrainStatus = 3
+	TrainStatus_TRAIN_STATUS_CANCELLED        TrainStatus = 4
+	TrainStatus_TRAIN_STATUS_NO_REPORT        TrainStatus = 5
+	TrainStatus_TRAIN_STATUS_STARTS_HERE      TrainStatus = 6
+	TrainStatus_TRAIN_STATUS_OFF_ROUTE        TrainStatus = 7
+	TrainStatus_TRAIN_STATUS_CHANGE_OF_ORIGIN TrainStatus = 8
+	TrainStatus_TRAIN_STATUS_REINSTATEMENT    TrainStatus = 9
+	TrainStatus_TRAIN_STATUS_ARRIVED          TrainStatus = 10
+	TrainStatus_TRAIN_STATUS_DEPARTED         TrainStatus = 11
+)
+
+var TrainStatus_name = map[int32]string{
+	0:  "TRAIN_STATUS_UNKNOWN",
+	1:  "TRAIN_STATUS_ON_TIME",
+	2:  "TRAIN_STATUS_EARLY",
+	3:  "TRAIN_STATUS_LATE",
+	4:  "TRAIN_STATUS_CANCELLED",
+	5:  "TRAIN_STATUS_NO_REPORT",
+	6:  "TRAIN_STATUS_STARTS_HERE",
+	7:  "TRAIN_STATUS_OFF_ROUTE",
+	8:  "TRAIN_STATUS_CHANGE_OF_ORIGIN",
+	9:  "TRAIN_STATUS_REINSTATEMENT",
+	10: "TRAIN_STATUS_ARRIVED",
+	11: "TRAIN_STATUS_DEPARTED",
+}
+
+var TrainStatus_value = map[string]int32{
+	"TRAIN_STATUS_UNKNOWN":          0,
+	"TRAIN_STATUS_ON_TIME":          1,
+	"TRAIN_STATUS_EARLY":            2,
+	"TRAIN_STATUS_LATE":             3,
+	"TRAIN_STATUS_CANCELLED":        4,
+	"TRAIN_STATUS_NO_REPORT":        5,
+	"TRAIN_STATUS_STARTS_HERE":      6,
+	"TRAIN_STATUS_OFF_ROUTE":        7,
+	"TRAIN_STATUS_CHANGE_OF_ORIGIN": 8,
+	"TRAIN_STATUS_REINSTATEMENT":    9,
+	"TRAIN_STATUS_ARRIVED":          10,
+	"TRAIN_STATUS_DEPARTED":         11,
+}
+
+func (x TrainStatus) String() string {
+	return proto.EnumName(TrainStatus_name, int32(x))
+}
+
+func (TrainStatus) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{0}
+}
+
+type StopType int32
+
+const (
+	StopType_STOP_TYPE_UNKNOWN      StopType = 0
+	StopType_STOP_TYPE_ORIGIN       StopType = 1
+	StopType_STOP_TYPE_INTERMEDIATE StopType = 2
+	StopType_STOP_TYPE_PASS         StopType = 3
+	StopType_STOP_TYPE_DESTINATION  StopType = 4
+)
+
+var StopType_name = map[int32]string{
+	0: "STOP_TYPE_UNKNOWN",
+	1: "STOP_TYPE_ORIGIN",
+	2: "STOP_TYPE_INTERMEDIATE",
+	3: "STOP_TYPE_PASS",
+	4: "STOP_TYPE_DESTINATION",
+}
+
+var StopType_value = map[string]int32{
+	"STOP_TYPE_UNKNOWN":      0,
+	"STOP_TYPE_ORIGIN":       1,
+	"STOP_TYPE_INTERMEDIATE": 2,
+	"STOP_TYPE_PASS":         3,
+	"STOP_TYPE_DESTINATION":  4,
+}
+
+func (x StopType) String() string {
+	return proto.EnumName(StopType_name, int32(x))
+}
+
+func (StopType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{1}
+}
+
+type DepartureUpdate_UpdateType int32
+
+const (
+	DepartureUpdate_BOARD           DepartureUpdate_UpdateType = 0
+	DepartureUpdate_NEW_SERVICE     DepartureUpdate_UpdateType = 1
+	DepartureUpdate_STATUS_CHANGE   DepartureUpdate_UpdateType = 2
+	DepartureUpdate_PLATFORM_CHANGE DepartureUpdate_UpdateType = 3
+	DepartureUpdate_DEPARTED        DepartureUpdate_UpdateType = 4
+)
+
+var DepartureUpdate_UpdateType_name = map[int32]string{
+	0: "BOARD",
+	1: "NEW_SERVICE",
+	2: "STATUS_CHANGE",
+	3: "PLATFORM_CHANGE",
+	4: "DEPARTED",
+}
+
+var DepartureUpdate_UpdateType_value = map[string]int32{
+	"BOARD":           0,
+	"NEW_SERVICE":     1,
+	"STATUS_CHANGE":   2,
+	"PLATFORM_CHANGE": 3,
+	"DEPARTED":        4,
+}
+
+func (x DepartureUpdate_UpdateType) String() string {
+	return proto.EnumName(DepartureUpdate_UpdateType_name, int32(x))
+}
+
+func (DepartureUpdate_UpdateType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{2, 0}
+}
+
 // Request message
 type TrainRequest struct {
 	From                 string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
@@ -367,135 +495,1674 @@ func (m *TrainResponse_TrainDeparture) GetStops() []*TrainResponse_TrainStop {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*TrainRequest)(nil), "trains.TrainRequest")
-	proto.RegisterType((*TrainResponse)(nil), "trains.TrainResponse")
-	proto.RegisterType((*TrainResponse_TrainStop)(nil), "trains.TrainResponse.TrainStop")
-	proto.RegisterType((*TrainResponse_TrainDeparture)(nil), "trains.TrainResponse.TrainDeparture")
+// Update message streamed by WatchDepartures.  The first update on every stream
+// is a BOARD carrying the full board; later updates carry the departure that changed.
+type DepartureUpdate struct {
+	Type                          DepartureUpdate_UpdateType    `protobuf:"varint,1,opt,name=type,proto3,enum=trains.DepartureUpdate_UpdateType" json:"type,omitempty"`
+	Board                         *TrainResponse                `protobuf:"bytes,2,opt,name=board,proto3" json:"board,omitempty"`
+	Departure                     *TrainResponse_TrainDeparture `protobuf:"bytes,3,opt,name=departure,proto3" json:"departure,omitempty"`
+	PreviousStatus                string                        `protobuf:"bytes,4,opt,name=previous_status,json=previousStatus,proto3" json:"previous_status,omitempty"`
+	PreviousExpectedDepartureTime string                        `protobuf:"bytes,5,opt,name=previous_expected_departure_time,json=previousExpectedDepartureTime,proto3" json:"previous_expected_departure_time,omitempty"`
+	PreviousPlatform              int32                         `protobuf:"varint,6,opt,name=previous_platform,json=previousPlatform,proto3" json:"previous_platform,omitempty"`
+	XXX_NoUnkeyedLiteral          struct{}                      `json:"-"`
+	XXX_unrecognized              []byte                        `json:"-"`
+	XXX_sizecache                 int32                         `json:"-"`
 }
 
-func init() { proto.RegisterFile("trains.proto", fileDescriptor_0af5513ca3cdfb34) }
+func (m *DepartureUpdate) Reset()         { *m = DepartureUpdate{} }
+func (m *DepartureUpdate) String() string { return proto.CompactTextString(m) }
+func (*DepartureUpdate) ProtoMessage()    {}
+func (*DepartureUpdate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{2}
+}
 
-var fileDescriptor_0af5513ca3cdfb34 = []byte{
-	// 634 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0x56, 0x9c, 0x9f, 0x26, 0x93, 0xb4, 0x54, 0x0b, 0x05, 0xcb, 0x48, 0xb4, 0xa4, 0x1c, 0xda,
-	0x8a, 0xc6, 0x90, 0x0a, 0x0e, 0x95, 0x90, 0x28, 0x44, 0xe2, 0x82, 0x40, 0x4a, 0xcb, 0xd9, 0xda,
-	0xda, 0x1b, 0x67, 0x21, 0xf6, 0x2e, 0xbb, 0xeb, 0x42, 0xae, 0x3c, 0x02, 0x5c, 0x78, 0x2f, 0x0e,
-	0xbc, 0x00, 0xcf, 0x81, 0x90, 0xc7, 0xeb, 0xd4, 0x49, 0x10, 0xe2, 0xe4, 0x99, 0x6f, 0x7e, 0xf6,
-	0xf3, 0x37, 0xb3, 0x0b, 0x3d, 0xa3, 0x28, 0x4f, 0xf5, 0x40, 0x2a, 0x61, 0x04, 0x69, 0x15, 0x9e,
-	0x77, 0x62, 0xa6, 0x5c, 0x45, 0x81, 0xa4, 0xca, 0xcc, 0xfd, 0x58, 0x88, 0x78, 0xc6, 0xa8, 0xe4,
-	0xda, 0x9a, 0x3e, 0x95, 0xdc, 0xa7, 0x69, 0x2a, 0x0c, 0x35, 0x5c, 0x94, 0xc5, 0xde, 0x43, 0xfc,
-	0x84, 0xc7, 0x31, 0x4b, 0x8f, 0xf5, 0x27, 0x1a, 0xc7, 0x4c, 0xf9, 0x42, 0x62, 0xc6, 0x7a, 0x76,
-	0x7f, 0x08, 0xbd, 0x8b, 0xfc, 0xb0, 0x31, 0xfb, 0x98, 0x31, 0x6d, 0x08, 0x81, 0xc6, 0x44, 0x89,
-	0xc4, 0xad, 0xed, 0xd5, 0x0e, 0x3a, 0x63, 0xb4, 0xc9, 0x16, 0x38, 0x46, 0xb8, 0x0e, 0x22, 0x8e,
-	0x11, 0xfd, 0xdf, 0x2d, 0xd8, 0xb4, 0x45, 0x5a, 0x8a, 0x54, 0x33, 0x72, 0x1f, 0x7a, 0xba, 0xe8,
-	0x1b, 0x84, 0x22, 0x62, 0xb6, 0xba, 0x6b, 0xb1, 0x97, 0x22, 0x62, 0xe4, 0x2e, 0x74, 0x22, 0xa6,
-	0x4d, 0x11, 0x2f, 0x7a, 0xb5, 0x73, 0x00, 0x83, 0x04, 0x1a, 0x11, 0x35, 0xcc, 0xad, 0x17, 0xa7,
-	0xe6, 0x36, 0xb9, 0x07, 0x5d, 0xc3, 0x13, 0x16, 0x88, 0x49, 0x10, 0xd1, 0xb9, 0xdb, 0xc0, 0x50,
-	0x27, 0x87, 0xde, 0x4e, 0x46, 0x74, 0x4e, 0x46, 0x00, 0x11, 0xcb, 0xa5, 0xc9, 0x14, 0xd3, 0x6e,
-	0x73, 0xaf, 0x7e, 0xd0, 0x1d, 0x3e, 0x18, 0x58, 0x1d, 0x97, 0xe8, 0x15, 0xde, 0xa8, 0x4c, 0x1e,
-	0x57, 0xea, 0xaa, 0xcc, 0x53, 0x9a, 0x30, 0xb7, 0xb5, 0xc4, 0xfc, 0x0d, 0x4d, 0xae, 0x99, 0x63,
-	0x7c, 0xe3, 0x9a, 0x79, 0x1e, 0xf4, 0xbe, 0xd7, 0xa0, 0x83, 0xed, 0xcf, 0x8d, 0x90, 0xff, 0xa3,
-	0xc3, 0xea, 0x81, 0xce, 0xfa, 0x81, 0x1e, 0xb4, 0xe5, 0x8c, 0x9a, 0x89, 0x50, 0x09, 0x2a, 0xd2,
-	0x1c, 0x2f, 0x7c, 0x72, 0x08, 0xdb, 0xec, 0xb3, 0x64, 0xa1, 0x61, 0x51, 0x40, 0x95, 0xe2, 0x57,
-	0x74, 0x66, 0xa5, 0xb9, 0x51, 0xe2, 0x67, 0x05, 0xec, 0xfd, 0xac, 0xc3, 0xd6, 0xf2, 0x9f, 0xe7,
-	0x3a, 0x27, 0xd7, 0xbc, 0xd0, 0x26, 0x2e, 0x6c, 0x68, 0xa6, 0xae, 0x78, 0x58, 0x72, 0x29, 0xdd,
-	0xfc, 0xc7, 0x51, 0xce, 0x20, 0xe3, 0x91, 0x1d, 0x4d, 0x1b, 0x81, 0x77, 0x3c, 0x5a, 0x22, 0xd9,
-	0x58, 0x21, 0xe9, 0x41, 0x5b, 0x48, 0xa6, 0xa8, 0x11, 0xca, 0x6d, 0x16, 0x75, 0xa5, 0x4f, 0xf6,
-	0x61, 0xb3, 0xb4, 0xab, 0x8a, 0xf7, 0x4a, 0x10, 0x15, 0xd8, 0x85, 0xae, 0x50, 0x3c, 0xe6, 0x69,
-	0x55, 0x74, 0x28, 0x20, 0x4c, 0x38, 0x84, 0xed, 0x7c, 0x04, 0x3c, 0xad, 0x28, 0xd9, 0x2e, 0x64,
-	0xa8, 0xe0, 0x98, 0x7a, 0x1b, 0x5a, 0x5a, 0x64, 0x2a, 0x64, 0x6e, 0x07, 0x13, 0xac, 0x87, 0xb8,
-	0xa1, 0x26, 0xd3, 0x2e, 0x58, 0x1c, 0x3d, 0x32, 0x84, 0x9d, 0x55, 0x85, 0x83, 0x7c, 0xeb, 0xdc,
-	0x2e, 0xa6, 0xdd, 0x5c, 0x91, 0xf9, 0x82, 0x27, 0x8c, 0x3c, 0x85, 0x3b, 0x8b, 0x9a, 0xc5, 0x72,
-	0x15, 0x55, 0x3d, 0xac, 0x5a, 0xb4, 0x5c, 0xcc, 0x02, 0xeb, 0x9e, 0x40, 0x53, 0x1b, 0x21, 0xb5,
-	0xbb, 0x89, 0xeb, 0xbb, 0xfb, 0x8f, 0xf5, 0xcd, 0xf7, 0x6b, 0x5c, 0x64, 0x0f, 0x2f, 0xec, 0xa5,
-	0x3d, 0xb7, 0x83, 0x1a, 0x41, 0xe7, 0x15, 0x33, 0x08, 0x69, 0x72, 0x6b, 0xa5, 0x09, 0xde, 0x6b,
-	0x6f, 0xe7, 0xaf, 0xad, 0xfb, 0xf0, 0xe5, 0xc7, 0xaf, 0x6f, 0x4e, 0x83, 0x38, 0xfe, 0xd1, 0x8b,
-	0xf9, 0xd7, 0xb3, 0x2b, 0xf2, 0x1a, 0xec, 0xe3, 0xd3, 0x7f, 0x56, 0x5a, 0x64, 0x7f, 0x6a, 0x8c,
-	0xd4, 0xa7, 0xbe, 0x1f, 0x73, 0x33, 0xcd, 0x2e, 0x07, 0xa1, 0x48, 0xfc, 0x84, 0xce, 0x12, 0x9e,
-	0x4e, 0xa9, 0xf6, 0xed, 0x5b, 0xb5, 0x95, 0xd0, 0xd9, 0xf3, 0x1c, 0x1d, 0x84, 0x62, 0x90, 0x7d,
-	0x18, 0xd6, 0x1f, 0x0f, 0x1e, 0x1d, 0x39, 0x35, 0x67, 0xb8, 0x4d, 0xa5, 0x9c, 0xf1, 0x10, 0x07,
-	0xe2, 0xbf, 0xd7, 0x22, 0x3d, 0x5d, 0x43, 0x2e, 0x5b, 0xf8, 0x18, 0x9d, 0xfc, 0x09, 0x00, 0x00,
-	0xff, 0xff, 0x9d, 0x11, 0x3b, 0xf9, 0x07, 0x05, 0x00, 0x00,
+func (m *DepartureUpdate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DepartureUpdate.Unmarshal(m, b)
+}
+func (m *DepartureUpdate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DepartureUpdate.Marshal(b, m, deterministic)
+}
+func (m *DepartureUpdate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DepartureUpdate.Merge(m, src)
+}
+func (m *DepartureUpdate) XXX_Size() int {
+	return xxx_messageInfo_DepartureUpdate.Size(m)
+}
+func (m *DepartureUpdate) XXX_DiscardUnknown() {
+	xxx_messageInfo_DepartureUpdate.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+var xxx_messageInfo_DepartureUpdate proto.InternalMessageInfo
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *DepartureUpdate) GetType() DepartureUpdate_UpdateType {
+	if m != nil {
+		return m.Type
+	}
+	return DepartureUpdate_BOARD
+}
 
-// TrainServiceClient is the client API for TrainService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type TrainServiceClient interface {
-	GetTrains(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponse, error)
+func (m *DepartureUpdate) GetBoard() *TrainResponse {
+	if m != nil {
+		return m.Board
+	}
+	return nil
 }
 
-type trainServiceClient struct {
-	cc *grpc.ClientConn
+func (m *DepartureUpdate) GetDeparture() *TrainResponse_TrainDeparture {
+	if m != nil {
+		return m.Departure
+	}
+	return nil
 }
 
-func NewTrainServiceClient(cc *grpc.ClientConn) TrainServiceClient {
-	return &trainServiceClient{cc}
+func (m *DepartureUpdate) GetPreviousStatus() string {
+	if m != nil {
+		return m.PreviousStatus
+	}
+	return ""
 }
 
-func (c *trainServiceClient) GetTrains(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponse, error) {
-	out := new(TrainResponse)
-	err := c.cc.Invoke(ctx, "/trains.TrainService/GetTrains", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *DepartureUpdate) GetPreviousExpectedDepartureTime() string {
+	if m != nil {
+		return m.PreviousExpectedDepartureTime
 	}
-	return out, nil
+	return ""
 }
 
-// TrainServiceServer is the server API for TrainService service.
-type TrainServiceServer interface {
-	GetTrains(context.Context, *TrainRequest) (*TrainResponse, error)
+func (m *DepartureUpdate) GetPreviousPlatform() int32 {
+	if m != nil {
+		return m.PreviousPlatform
+	}
+	return 0
 }
 
-// UnimplementedTrainServiceServer can be embedded to have forward compatible implementations.
-type UnimplementedTrainServiceServer struct {
+// Service timetable messages
+type ServiceRequest struct {
+	TrainUid             string   `protobuf:"bytes,1,opt,name=train_uid,json=trainUid,proto3" json:"train_uid,omitempty"`
+	Date                 string   `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (*UnimplementedTrainServiceServer) GetTrains(ctx context.Context, req *TrainRequest) (*TrainResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetTrains not implemented")
+func (m *ServiceRequest) Reset()         { *m = ServiceRequest{} }
+func (m *ServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*ServiceRequest) ProtoMessage()    {}
+func (*ServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{3}
 }
 
-func RegisterTrainServiceServer(s *grpc.Server, srv TrainServiceServer) {
-	s.RegisterService(&_TrainService_serviceDesc, srv)
+func (m *ServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceRequest.Unmarshal(m, b)
+}
+func (m *ServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceRequest.Marshal(b, m, deterministic)
+}
+func (m *ServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceRequest.Merge(m, src)
+}
+func (m *ServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_ServiceRequest.Size(m)
+}
+func (m *ServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceRequest.DiscardUnknown(m)
 }
 
-func _TrainService_GetTrains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(TrainRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+var xxx_messageInfo_ServiceRequest proto.InternalMessageInfo
+
+func (m *ServiceRequest) GetTrainUid() string {
+	if m != nil {
+		return m.TrainUid
 	}
-	if interceptor == nil {
-		return srv.(TrainServiceServer).GetTrains(ctx, in)
+	return ""
+}
+
+func (m *ServiceRequest) GetDate() string {
+	if m != nil {
+		return m.Date
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/trains.TrainService/GetTrains",
+	return ""
+}
+
+type ServiceResponse struct {
+	TrainUid             string                         `protobuf:"bytes,1,opt,name=train_uid,json=trainUid,proto3" json:"train_uid,omitempty"`
+	Service              string                         `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Headcode             string                         `protobuf:"bytes,3,opt,name=headcode,proto3" json:"headcode,omitempty"`
+	TrainStatus          string                         `protobuf:"bytes,4,opt,name=train_status,json=trainStatus,proto3" json:"train_status,omitempty"`
+	Operator             string                         `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	OperatorName         string                         `protobuf:"bytes,6,opt,name=operator_name,json=operatorName,proto3" json:"operator_name,omitempty"`
+	OriginName           string                         `protobuf:"bytes,7,opt,name=origin_name,json=originName,proto3" json:"origin_name,omitempty"`
+	DestinationName      string                         `protobuf:"bytes,8,opt,name=destination_name,json=destinationName,proto3" json:"destination_name,omitempty"`
+	Date                 string                         `protobuf:"bytes,9,opt,name=date,proto3" json:"date,omitempty"`
+	Category             string                         `protobuf:"bytes,10,opt,name=category,proto3" json:"category,omitempty"`
+	Stops                []*ServiceResponse_ServiceStop `protobuf:"bytes,11,rep,name=stops,proto3" json:"stops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                       `json:"-"`
+	XXX_unrecognized     []byte                         `json:"-"`
+	XXX_sizecache        int32                          `json:"-"`
+}
+
+func (m *ServiceResponse) Reset()         { *m = ServiceResponse{} }
+func (m *ServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ServiceResponse) ProtoMessage()    {}
+func (*ServiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{4}
+}
+
+func (m *ServiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceResponse.Unmarshal(m, b)
+}
+func (m *ServiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceResponse.Marshal(b, m, deterministic)
+}
+func (m *ServiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceResponse.Merge(m, src)
+}
+func (m *ServiceResponse) XXX_Size() int {
+	return xxx_messageInfo_ServiceResponse.Size(m)
+}
+func (m *ServiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceResponse proto.InternalMessageInfo
+
+func (m *ServiceResponse) GetTrainUid() string {
+	if m != nil {
+		return m.TrainUid
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TrainServiceServer).GetTrains(ctx, req.(*TrainRequest))
+	return ""
+}
+
+func (m *ServiceResponse) GetService() string {
+	if m != nil {
+		return m.Service
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-var _TrainService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "trains.TrainService",
-	HandlerType: (*TrainServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetTrains",
-			Handler:    _TrainService_GetTrains_Handler,
+func (m *ServiceResponse) GetHeadcode() string {
+	if m != nil {
+		return m.Headcode
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetTrainStatus() string {
+	if m != nil {
+		return m.TrainStatus
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetOperatorName() string {
+	if m != nil {
+		return m.OperatorName
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetOriginName() string {
+	if m != nil {
+		return m.OriginName
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetDestinationName() string {
+	if m != nil {
+		return m.DestinationName
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetDate() string {
+	if m != nil {
+		return m.Date
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *ServiceResponse) GetStops() []*ServiceResponse_ServiceStop {
+	if m != nil {
+		return m.Stops
+	}
+	return nil
+}
+
+type ServiceResponse_ServiceStop struct {
+	StationCode           string   `protobuf:"bytes,1,opt,name=station_code,json=stationCode,proto3" json:"station_code,omitempty"`
+	TiplocCode            string   `protobuf:"bytes,2,opt,name=tiploc_code,json=tiplocCode,proto3" json:"tiploc_code,omitempty"`
+	StationName           string   `protobuf:"bytes,3,opt,name=station_name,json=stationName,proto3" json:"station_name,omitempty"`
+	StopType              string   `protobuf:"bytes,4,opt,name=stop_type,json=stopType,proto3" json:"stop_type,omitempty"`
+	Platform              string   `protobuf:"bytes,5,opt,name=platform,proto3" json:"platform,omitempty"`
+	AimedArrivalTime      string   `protobuf:"bytes,6,opt,name=aimed_arrival_time,json=aimedArrivalTime,proto3" json:"aimed_arrival_time,omitempty"`
+	AimedDepartureTime    string   `protobuf:"bytes,7,opt,name=aimed_departure_time,json=aimedDepartureTime,proto3" json:"aimed_departure_time,omitempty"`
+	AimedPassTime         string   `protobuf:"bytes,8,opt,name=aimed_pass_time,json=aimedPassTime,proto3" json:"aimed_pass_time,omitempty"`
+	ExpectedArrivalTime   string   `protobuf:"bytes,9,opt,name=expected_arrival_time,json=expectedArrivalTime,proto3" json:"expected_arrival_time,omitempty"`
+	ExpectedDepartureTime string   `protobuf:"bytes,10,opt,name=expected_departure_time,json=expectedDepartureTime,proto3" json:"expected_departure_time,omitempty"`
+	ExpectedPassTime      string   `protobuf:"bytes,11,opt,name=expected_pass_time,json=expectedPassTime,proto3" json:"expected_pass_time,omitempty"`
+	Status                string   `protobuf:"bytes,12,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral  struct{} `json:"-"`
+	XXX_unrecognized      []byte   `json:"-"`
+	XXX_sizecache         int32    `json:"-"`
+}
+
+func (m *ServiceResponse_ServiceStop) Reset()         { *m = ServiceResponse_ServiceStop{} }
+func (m *ServiceResponse_ServiceStop) String() string { return proto.CompactTextString(m) }
+func (*ServiceResponse_ServiceStop) ProtoMessage()    {}
+func (*ServiceResponse_ServiceStop) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{4, 0}
+}
+
+func (m *ServiceResponse_ServiceStop) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceResponse_ServiceStop.Unmarshal(m, b)
+}
+func (m *ServiceResponse_ServiceStop) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceResponse_ServiceStop.Marshal(b, m, deterministic)
+}
+func (m *ServiceResponse_ServiceStop) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceResponse_ServiceStop.Merge(m, src)
+}
+func (m *ServiceResponse_ServiceStop) XXX_Size() int {
+	return xxx_messageInfo_ServiceResponse_ServiceStop.Size(m)
+}
+func (m *ServiceResponse_ServiceStop) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceResponse_ServiceStop.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceResponse_ServiceStop proto.InternalMessageInfo
+
+func (m *ServiceResponse_ServiceStop) GetStationCode() string {
+	if m != nil {
+		return m.StationCode
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetTiplocCode() string {
+	if m != nil {
+		return m.TiplocCode
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetStationName() string {
+	if m != nil {
+		return m.StationName
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetStopType() string {
+	if m != nil {
+		return m.StopType
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetAimedArrivalTime() string {
+	if m != nil {
+		return m.AimedArrivalTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetAimedDepartureTime() string {
+	if m != nil {
+		return m.AimedDepartureTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetAimedPassTime() string {
+	if m != nil {
+		return m.AimedPassTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetExpectedArrivalTime() string {
+	if m != nil {
+		return m.ExpectedArrivalTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetExpectedDepartureTime() string {
+	if m != nil {
+		return m.ExpectedDepartureTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetExpectedPassTime() string {
+	if m != nil {
+		return m.ExpectedPassTime
+	}
+	return ""
+}
+
+func (m *ServiceResponse_ServiceStop) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+type TrainStopV2 struct {
+	StationCode          string               `protobuf:"bytes,1,opt,name=station_code,json=stationCode,proto3" json:"station_code,omitempty"`
+	TiplocCode           string               `protobuf:"bytes,2,opt,name=tiploc_code,json=tiplocCode,proto3" json:"tiploc_code,omitempty"`
+	StationName          string               `protobuf:"bytes,3,opt,name=station_name,json=stationName,proto3" json:"station_name,omitempty"`
+	StopType             StopType             `protobuf:"varint,4,opt,name=stop_type,json=stopType,proto3,enum=trains.StopType" json:"stop_type,omitempty"`
+	Platform             string               `protobuf:"bytes,5,opt,name=platform,proto3" json:"platform,omitempty"`
+	AimedArrival         *timestamp.Timestamp `protobuf:"bytes,6,opt,name=aimed_arrival,json=aimedArrival,proto3" json:"aimed_arrival,omitempty"`
+	AimedDeparture       *timestamp.Timestamp `protobuf:"bytes,7,opt,name=aimed_departure,json=aimedDeparture,proto3" json:"aimed_departure,omitempty"`
+	AimedPass            *timestamp.Timestamp `protobuf:"bytes,8,opt,name=aimed_pass,json=aimedPass,proto3" json:"aimed_pass,omitempty"`
+	ExpectedArrival      *timestamp.Timestamp `protobuf:"bytes,9,opt,name=expected_arrival,json=expectedArrival,proto3" json:"expected_arrival,omitempty"`
+	ExpectedDeparture    *timestamp.Timestamp `protobuf:"bytes,10,opt,name=expected_departure,json=expectedDeparture,proto3" json:"expected_departure,omitempty"`
+	ExpectedPass         *timestamp.Timestamp `protobuf:"bytes,11,opt,name=expected_pass,json=expectedPass,proto3" json:"expected_pass,omitempty"`
+	Delay                *duration.Duration   `protobuf:"bytes,12,opt,name=delay,proto3" json:"delay,omitempty"`
+	Status               TrainStatus          `protobuf:"varint,13,opt,name=status,proto3,enum=trains.TrainStatus" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *TrainStopV2) Reset()         { *m = TrainStopV2{} }
+func (m *TrainStopV2) String() string { return proto.CompactTextString(m) }
+func (*TrainStopV2) ProtoMessage()    {}
+func (*TrainStopV2) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{5}
+}
+
+func (m *TrainStopV2) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrainStopV2.Unmarshal(m, b)
+}
+func (m *TrainStopV2) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrainStopV2.Marshal(b, m, deterministic)
+}
+func (m *TrainStopV2) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrainStopV2.Merge(m, src)
+}
+func (m *TrainStopV2) XXX_Size() int {
+	return xxx_messageInfo_TrainStopV2.Size(m)
+}
+func (m *TrainStopV2) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrainStopV2.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TrainStopV2 proto.InternalMessageInfo
+
+func (m *TrainStopV2) GetStationCode() string {
+	if m != nil {
+		return m.StationCode
+	}
+	return ""
+}
+
+func (m *TrainStopV2) GetTiplocCode() string {
+	if m != nil {
+		return m.TiplocCode
+	}
+	return ""
+}
+
+func (m *TrainStopV2) GetStationName() string {
+	if m != nil {
+		return m.StationName
+	}
+	return ""
+}
+
+func (m *TrainStopV2) GetStopType() StopType {
+	if m != nil {
+		return m.StopType
+	}
+	return StopType_STOP_TYPE_UNKNOWN
+}
+
+func (m *TrainStopV2) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *TrainStopV2) GetAimedArrival() *timestamp.Timestamp {
+	if m != nil {
+		return m.AimedArrival
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetAimedDeparture() *timestamp.Timestamp {
+	if m != nil {
+		return m.AimedDeparture
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetAimedPass() *timestamp.Timestamp {
+	if m != nil {
+		return m.AimedPass
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetExpectedArrival() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpectedArrival
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetExpectedDeparture() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpectedDeparture
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetExpectedPass() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpectedPass
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetDelay() *duration.Duration {
+	if m != nil {
+		return m.Delay
+	}
+	return nil
+}
+
+func (m *TrainStopV2) GetStatus() TrainStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TrainStatus_TRAIN_STATUS_UNKNOWN
+}
+
+type TrainDepartureV2 struct {
+	Mode                 string               `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	Service              string               `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	TrainUid             string               `protobuf:"bytes,3,opt,name=train_uid,json=trainUid,proto3" json:"train_uid,omitempty"`
+	Platform             string               `protobuf:"bytes,4,opt,name=platform,proto3" json:"platform,omitempty"`
+	Operator             string               `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	OperatorName         string               `protobuf:"bytes,6,opt,name=operator_name,json=operatorName,proto3" json:"operator_name,omitempty"`
+	OriginName           string               `protobuf:"bytes,7,opt,name=origin_name,json=originName,proto3" json:"origin_name,omitempty"`
+	DestinationName      string               `protobuf:"bytes,8,opt,name=destination_name,json=destinationName,proto3" json:"destination_name,omitempty"`
+	Source               string               `protobuf:"bytes,9,opt,name=source,proto3" json:"source,omitempty"`
+	Category             string               `protobuf:"bytes,10,opt,name=category,proto3" json:"category,omitempty"`
+	Status               TrainStatus          `protobuf:"varint,11,opt,name=status,proto3,enum=trains.TrainStatus" json:"status,omitempty"`
+	AimedDeparture       *timestamp.Timestamp `protobuf:"bytes,12,opt,name=aimed_departure,json=aimedDeparture,proto3" json:"aimed_departure,omitempty"`
+	ExpectedDeparture    *timestamp.Timestamp `protobuf:"bytes,13,opt,name=expected_departure,json=expectedDeparture,proto3" json:"expected_departure,omitempty"`
+	Delay                *duration.Duration   `protobuf:"bytes,14,opt,name=delay,proto3" json:"delay,omitempty"`
+	Stops                []*TrainStopV2       `protobuf:"bytes,15,rep,name=stops,proto3" json:"stops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *TrainDepartureV2) Reset()         { *m = TrainDepartureV2{} }
+func (m *TrainDepartureV2) String() string { return proto.CompactTextString(m) }
+func (*TrainDepartureV2) ProtoMessage()    {}
+func (*TrainDepartureV2) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{6}
+}
+
+func (m *TrainDepartureV2) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrainDepartureV2.Unmarshal(m, b)
+}
+func (m *TrainDepartureV2) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrainDepartureV2.Marshal(b, m, deterministic)
+}
+func (m *TrainDepartureV2) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrainDepartureV2.Merge(m, src)
+}
+func (m *TrainDepartureV2) XXX_Size() int {
+	return xxx_messageInfo_TrainDepartureV2.Size(m)
+}
+func (m *TrainDepartureV2) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrainDepartureV2.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TrainDepartureV2 proto.InternalMessageInfo
+
+func (m *TrainDepartureV2) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetTrainUid() string {
+	if m != nil {
+		return m.TrainUid
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetOperatorName() string {
+	if m != nil {
+		return m.OperatorName
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetOriginName() string {
+	if m != nil {
+		return m.OriginName
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetDestinationName() string {
+	if m != nil {
+		return m.DestinationName
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *TrainDepartureV2) GetStatus() TrainStatus {
+	if m != nil {
+		return m.Status
+	}
+	return TrainStatus_TRAIN_STATUS_UNKNOWN
+}
+
+func (m *TrainDepartureV2) GetAimedDeparture() *timestamp.Timestamp {
+	if m != nil {
+		return m.AimedDeparture
+	}
+	return nil
+}
+
+func (m *TrainDepartureV2) GetExpectedDeparture() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpectedDeparture
+	}
+	return nil
+}
+
+func (m *TrainDepartureV2) GetDelay() *duration.Duration {
+	if m != nil {
+		return m.Delay
+	}
+	return nil
+}
+
+func (m *TrainDepartureV2) GetStops() []*TrainStopV2 {
+	if m != nil {
+		return m.Stops
+	}
+	return nil
+}
+
+type TrainResponseV2 struct {
+	StationCode          string               `protobuf:"bytes,1,opt,name=station_code,json=stationCode,proto3" json:"station_code,omitempty"`
+	StationName          string               `protobuf:"bytes,2,opt,name=station_name,json=stationName,proto3" json:"station_name,omitempty"`
+	DestCode             string               `protobuf:"bytes,3,opt,name=dest_code,json=destCode,proto3" json:"dest_code,omitempty"`
+	DestName             string               `protobuf:"bytes,4,opt,name=dest_name,json=destName,proto3" json:"dest_name,omitempty"`
+	RequestTime          *timestamp.Timestamp `protobuf:"bytes,5,opt,name=request_time,json=requestTime,proto3" json:"request_time,omitempty"`
+	Departures           []*TrainDepartureV2  `protobuf:"bytes,6,rep,name=departures,proto3" json:"departures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *TrainResponseV2) Reset()         { *m = TrainResponseV2{} }
+func (m *TrainResponseV2) String() string { return proto.CompactTextString(m) }
+func (*TrainResponseV2) ProtoMessage()    {}
+func (*TrainResponseV2) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{7}
+}
+
+func (m *TrainResponseV2) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrainResponseV2.Unmarshal(m, b)
+}
+func (m *TrainResponseV2) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrainResponseV2.Marshal(b, m, deterministic)
+}
+func (m *TrainResponseV2) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrainResponseV2.Merge(m, src)
+}
+func (m *TrainResponseV2) XXX_Size() int {
+	return xxx_messageInfo_TrainResponseV2.Size(m)
+}
+func (m *TrainResponseV2) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrainResponseV2.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TrainResponseV2 proto.InternalMessageInfo
+
+func (m *TrainResponseV2) GetStationCode() string {
+	if m != nil {
+		return m.StationCode
+	}
+	return ""
+}
+
+func (m *TrainResponseV2) GetStationName() string {
+	if m != nil {
+		return m.StationName
+	}
+	return ""
+}
+
+func (m *TrainResponseV2) GetDestCode() string {
+	if m != nil {
+		return m.DestCode
+	}
+	return ""
+}
+
+func (m *TrainResponseV2) GetDestName() string {
+	if m != nil {
+		return m.DestName
+	}
+	return ""
+}
+
+func (m *TrainResponseV2) GetRequestTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.RequestTime
+	}
+	return nil
+}
+
+func (m *TrainResponseV2) GetDepartures() []*TrainDepartureV2 {
+	if m != nil {
+		return m.Departures
+	}
+	return nil
+}
+
+type ServiceResponseV2 struct {
+	TrainUid             string         `protobuf:"bytes,1,opt,name=train_uid,json=trainUid,proto3" json:"train_uid,omitempty"`
+	Service              string         `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Headcode             string         `protobuf:"bytes,3,opt,name=headcode,proto3" json:"headcode,omitempty"`
+	TrainStatus          TrainStatus    `protobuf:"varint,4,opt,name=train_status,json=trainStatus,proto3,enum=trains.TrainStatus" json:"train_status,omitempty"`
+	Operator             string         `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	OperatorName         string         `protobuf:"bytes,6,opt,name=operator_name,json=operatorName,proto3" json:"operator_name,omitempty"`
+	OriginName           string         `protobuf:"bytes,7,opt,name=origin_name,json=originName,proto3" json:"origin_name,omitempty"`
+	DestinationName      string         `protobuf:"bytes,8,opt,name=destination_name,json=destinationName,proto3" json:"destination_name,omitempty"`
+	Date                 string         `protobuf:"bytes,9,opt,name=date,proto3" json:"date,omitempty"`
+	Category             string         `protobuf:"bytes,10,opt,name=category,proto3" json:"category,omitempty"`
+	Stops                []*TrainStopV2 `protobuf:"bytes,11,rep,name=stops,proto3" json:"stops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ServiceResponseV2) Reset()         { *m = ServiceResponseV2{} }
+func (m *ServiceResponseV2) String() string { return proto.CompactTextString(m) }
+func (*ServiceResponseV2) ProtoMessage()    {}
+func (*ServiceResponseV2) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{8}
+}
+
+func (m *ServiceResponseV2) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceResponseV2.Unmarshal(m, b)
+}
+func (m *ServiceResponseV2) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceResponseV2.Marshal(b, m, deterministic)
+}
+func (m *ServiceResponseV2) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceResponseV2.Merge(m, src)
+}
+func (m *ServiceResponseV2) XXX_Size() int {
+	return xxx_messageInfo_ServiceResponseV2.Size(m)
+}
+func (m *ServiceResponseV2) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceResponseV2.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceResponseV2 proto.InternalMessageInfo
+
+func (m *ServiceResponseV2) GetTrainUid() string {
+	if m != nil {
+		return m.TrainUid
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetHeadcode() string {
+	if m != nil {
+		return m.Headcode
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetTrainStatus() TrainStatus {
+	if m != nil {
+		return m.TrainStatus
+	}
+	return TrainStatus_TRAIN_STATUS_UNKNOWN
+}
+
+func (m *ServiceResponseV2) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetOperatorName() string {
+	if m != nil {
+		return m.OperatorName
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetOriginName() string {
+	if m != nil {
+		return m.OriginName
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetDestinationName() string {
+	if m != nil {
+		return m.DestinationName
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetDate() string {
+	if m != nil {
+		return m.Date
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *ServiceResponseV2) GetStops() []*TrainStopV2 {
+	if m != nil {
+		return m.Stops
+	}
+	return nil
+}
+
+// Station registry messages
+type Station struct {
+	CrsCode              string   `protobuf:"bytes,1,opt,name=crs_code,json=crsCode,proto3" json:"crs_code,omitempty"`
+	StationName          string   `protobuf:"bytes,2,opt,name=station_name,json=stationName,proto3" json:"station_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Station) Reset()         { *m = Station{} }
+func (m *Station) String() string { return proto.CompactTextString(m) }
+func (*Station) ProtoMessage()    {}
+func (*Station) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{9}
+}
+
+func (m *Station) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Station.Unmarshal(m, b)
+}
+func (m *Station) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Station.Marshal(b, m, deterministic)
+}
+func (m *Station) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Station.Merge(m, src)
+}
+func (m *Station) XXX_Size() int {
+	return xxx_messageInfo_Station.Size(m)
+}
+func (m *Station) XXX_DiscardUnknown() {
+	xxx_messageInfo_Station.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Station proto.InternalMessageInfo
+
+func (m *Station) GetCrsCode() string {
+	if m != nil {
+		return m.CrsCode
+	}
+	return ""
+}
+
+func (m *Station) GetStationName() string {
+	if m != nil {
+		return m.StationName
+	}
+	return ""
+}
+
+type ListStationsRequest struct {
+	PageSize             int32    `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListStationsRequest) Reset()         { *m = ListStationsRequest{} }
+func (m *ListStationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListStationsRequest) ProtoMessage()    {}
+func (*ListStationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{10}
+}
+
+func (m *ListStationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStationsRequest.Unmarshal(m, b)
+}
+func (m *ListStationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStationsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListStationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStationsRequest.Merge(m, src)
+}
+func (m *ListStationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListStationsRequest.Size(m)
+}
+func (m *ListStationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStationsRequest proto.InternalMessageInfo
+
+func (m *ListStationsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListStationsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type ListStationsResponse struct {
+	Stations             []*Station `protobuf:"bytes,1,rep,name=stations,proto3" json:"stations,omitempty"`
+	NextPageToken        string     `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalSize            int32      `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListStationsResponse) Reset()         { *m = ListStationsResponse{} }
+func (m *ListStationsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListStationsResponse) ProtoMessage()    {}
+func (*ListStationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{11}
+}
+
+func (m *ListStationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStationsResponse.Unmarshal(m, b)
+}
+func (m *ListStationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStationsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListStationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStationsResponse.Merge(m, src)
+}
+func (m *ListStationsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListStationsResponse.Size(m)
+}
+func (m *ListStationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStationsResponse proto.InternalMessageInfo
+
+func (m *ListStationsResponse) GetStations() []*Station {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
+}
+
+func (m *ListStationsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *ListStationsResponse) GetTotalSize() int32 {
+	if m != nil {
+		return m.TotalSize
+	}
+	return 0
+}
+
+type GetStationRequest struct {
+	CrsCode              string   `protobuf:"bytes,1,opt,name=crs_code,json=crsCode,proto3" json:"crs_code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStationRequest) Reset()         { *m = GetStationRequest{} }
+func (m *GetStationRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStationRequest) ProtoMessage()    {}
+func (*GetStationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{12}
+}
+
+func (m *GetStationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStationRequest.Unmarshal(m, b)
+}
+func (m *GetStationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStationRequest.Marshal(b, m, deterministic)
+}
+func (m *GetStationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStationRequest.Merge(m, src)
+}
+func (m *GetStationRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStationRequest.Size(m)
+}
+func (m *GetStationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStationRequest proto.InternalMessageInfo
+
+func (m *GetStationRequest) GetCrsCode() string {
+	if m != nil {
+		return m.CrsCode
+	}
+	return ""
+}
+
+// Query matches CRS codes exactly and station names fuzzily, best matches first
+type SearchStationsRequest struct {
+	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	PageSize             int32    `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken            string   `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchStationsRequest) Reset()         { *m = SearchStationsRequest{} }
+func (m *SearchStationsRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchStationsRequest) ProtoMessage()    {}
+func (*SearchStationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{13}
+}
+
+func (m *SearchStationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchStationsRequest.Unmarshal(m, b)
+}
+func (m *SearchStationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchStationsRequest.Marshal(b, m, deterministic)
+}
+func (m *SearchStationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchStationsRequest.Merge(m, src)
+}
+func (m *SearchStationsRequest) XXX_Size() int {
+	return xxx_messageInfo_SearchStationsRequest.Size(m)
+}
+func (m *SearchStationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchStationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchStationsRequest proto.InternalMessageInfo
+
+func (m *SearchStationsRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *SearchStationsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *SearchStationsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type SearchStationsResponse struct {
+	Stations             []*Station `protobuf:"bytes,1,rep,name=stations,proto3" json:"stations,omitempty"`
+	NextPageToken        string     `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalSize            int32      `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SearchStationsResponse) Reset()         { *m = SearchStationsResponse{} }
+func (m *SearchStationsResponse) String() string { return proto.CompactTextString(m) }
+func (*SearchStationsResponse) ProtoMessage()    {}
+func (*SearchStationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0af5513ca3cdfb34, []int{14}
+}
+
+func (m *SearchStationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchStationsResponse.Unmarshal(m, b)
+}
+func (m *SearchStationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchStationsResponse.Marshal(b, m, deterministic)
+}
+func (m *SearchStationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchStationsResponse.Merge(m, src)
+}
+func (m *SearchStationsResponse) XXX_Size() int {
+	return xxx_messageInfo_SearchStationsResponse.Size(m)
+}
+func (m *SearchStationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchStationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchStationsResponse proto.InternalMessageInfo
+
+func (m *SearchStationsResponse) GetStations() []*Station {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
+}
+
+func (m *SearchStationsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *SearchStationsResponse) GetTotalSize() int32 {
+	if m != nil {
+		return m.TotalSize
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("trains.TrainStatus", TrainStatus_name, TrainStatus_value)
+	proto.RegisterEnum("trains.StopType", StopType_name, StopType_value)
+	proto.RegisterEnum("trains.DepartureUpdate_UpdateType", DepartureUpdate_UpdateType_name, DepartureUpdate_UpdateType_value)
+	proto.RegisterType((*TrainRequest)(nil), "trains.TrainRequest")
+	proto.RegisterType((*TrainResponse)(nil), "trains.TrainResponse")
+	proto.RegisterType((*TrainResponse_TrainStop)(nil), "trains.TrainResponse.TrainStop")
+	proto.RegisterType((*TrainResponse_TrainDeparture)(nil), "trains.TrainResponse.TrainDeparture")
+	proto.RegisterType((*DepartureUpdate)(nil), "trains.DepartureUpdate")
+	proto.RegisterType((*ServiceRequest)(nil), "trains.ServiceRequest")
+	proto.RegisterType((*ServiceResponse)(nil), "trains.ServiceResponse")
+	proto.RegisterType((*ServiceResponse_ServiceStop)(nil), "trains.ServiceResponse.ServiceStop")
+	proto.RegisterType((*TrainStopV2)(nil), "trains.TrainStopV2")
+	proto.RegisterType((*TrainDepartureV2)(nil), "trains.TrainDepartureV2")
+	proto.RegisterType((*TrainResponseV2)(nil), "trains.TrainResponseV2")
+	proto.RegisterType((*ServiceResponseV2)(nil), "trains.ServiceResponseV2")
+	proto.RegisterType((*Station)(nil), "trains.Station")
+	proto.RegisterType((*ListStationsRequest)(nil), "trains.ListStationsRequest")
+	proto.RegisterType((*ListStationsResponse)(nil), "trains.ListStationsResponse")
+	proto.RegisterType((*GetStationRequest)(nil), "trains.GetStationRequest")
+	proto.RegisterType((*SearchStationsRequest)(nil), "trains.SearchStationsRequest")
+	proto.RegisterType((*SearchStationsResponse)(nil), "trains.SearchStationsResponse")
+}
+
+func init() { proto.RegisterFile("trains.proto", fileDescriptor_0af5513ca3cdfb34) }
+
+var fileDescriptor_0af5513ca3cdfb34 = []byte{
+	// 1961 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x59, 0x4b, 0x6f, 0xe3, 0xd6,
+	0x15, 0x8e, 0x24, 0xcb, 0x96, 0x0e, 0xf5, 0xa0, 0xef, 0xd8, 0x1e, 0x9a, 0x93, 0x19, 0x4f, 0x38,
+	0x45, 0x9b, 0x79, 0x49, 0x13, 0x06, 0x1d, 0x34, 0x03, 0x04, 0xad, 0xc6, 0xe2, 0x38, 0x42, 0x3d,
+	0x92, 0x4a, 0xd1, 0x1e, 0x0c, 0x50, 0x94, 0xe0, 0x48, 0xb4, 0xcc, 0x46, 0x12, 0x19, 0x92, 0x72,
+	0xa2, 0x04, 0xd9, 0x64, 0xd1, 0x45, 0xd0, 0x55, 0xbb, 0xe9, 0xdf, 0xe8, 0xb2, 0xbf, 0x23, 0x8b,
+	0x02, 0x5d, 0x17, 0x28, 0xfa, 0x1f, 0x8a, 0xa2, 0xb8, 0x2f, 0xbe, 0x24, 0xd9, 0x6e, 0x80, 0xf4,
+	0x91, 0x95, 0x79, 0xcf, 0x39, 0xf7, 0xdc, 0x73, 0xcf, 0xf9, 0xce, 0xe3, 0xca, 0x50, 0x09, 0x7d,
+	0xcb, 0x99, 0x05, 0x0d, 0xcf, 0x77, 0x43, 0x17, 0x6d, 0xd2, 0x95, 0xfc, 0x7e, 0x78, 0xee, 0xf8,
+	0x23, 0xd3, 0xb3, 0xfc, 0x70, 0xd1, 0x1c, 0xbb, 0xee, 0x78, 0x62, 0x5b, 0x9e, 0x13, 0xb0, 0xcf,
+	0xa6, 0xe5, 0x39, 0x4d, 0x6b, 0x36, 0x73, 0x43, 0x2b, 0x74, 0x5c, 0xbe, 0x59, 0x7e, 0x44, 0xfe,
+	0x0c, 0x1f, 0x8f, 0xed, 0xd9, 0xe3, 0xe0, 0x53, 0x6b, 0x3c, 0xb6, 0xfd, 0xa6, 0xeb, 0x11, 0x89,
+	0x15, 0xd2, 0x07, 0x4c, 0x17, 0x59, 0xbd, 0x99, 0x9f, 0x35, 0x43, 0x67, 0x6a, 0x07, 0xa1, 0x35,
+	0xf5, 0x98, 0xc0, 0x9d, 0xac, 0xc0, 0x68, 0xee, 0x13, 0x0d, 0x94, 0xaf, 0xa8, 0x50, 0x31, 0xb0,
+	0xb5, 0xba, 0xfd, 0xc9, 0xdc, 0x0e, 0x42, 0x84, 0x60, 0xe3, 0xcc, 0x77, 0xa7, 0x52, 0xee, 0x6e,
+	0xee, 0xdd, 0xb2, 0x4e, 0xbe, 0x51, 0x0d, 0xf2, 0xa1, 0x2b, 0xe5, 0x09, 0x25, 0x1f, 0xba, 0xca,
+	0x3f, 0x37, 0xa1, 0xca, 0x36, 0x05, 0x9e, 0x3b, 0x0b, 0x6c, 0xf4, 0x0e, 0x54, 0x02, 0x6a, 0x98,
+	0x39, 0x74, 0x47, 0x36, 0xdb, 0x2d, 0x30, 0xda, 0xa1, 0x3b, 0xb2, 0xd1, 0x2d, 0x28, 0x8f, 0xec,
+	0x20, 0xa4, 0x7c, 0xaa, 0xab, 0x84, 0x09, 0x84, 0x89, 0x60, 0x63, 0x64, 0x85, 0xb6, 0x54, 0xa0,
+	0xa7, 0xe2, 0x6f, 0x74, 0x07, 0x04, 0x7c, 0x19, 0xd3, 0x3d, 0x33, 0x47, 0xd6, 0x42, 0xda, 0x20,
+	0xac, 0x32, 0x26, 0xf5, 0xce, 0xda, 0xd6, 0x02, 0xb5, 0x01, 0x46, 0x36, 0xf6, 0xed, 0xdc, 0xb7,
+	0x03, 0xa9, 0x78, 0xb7, 0xf0, 0xae, 0xa0, 0xfe, 0xa0, 0xc1, 0x02, 0x91, 0x32, 0x8f, 0xae, 0xda,
+	0x5c, 0x58, 0x4f, 0xec, 0x4b, 0x5a, 0x3e, 0xb3, 0xa6, 0xb6, 0xb4, 0x99, 0xb2, 0xbc, 0x6b, 0x4d,
+	0x63, 0xcb, 0x09, 0x7f, 0x2b, 0xb6, 0x1c, 0x33, 0xe5, 0x3f, 0xe4, 0xa0, 0x4c, 0xd4, 0x0f, 0x42,
+	0xd7, 0xbb, 0x8e, 0x1f, 0xb2, 0x07, 0xe6, 0x97, 0x0f, 0x94, 0xa1, 0xe4, 0x4d, 0xac, 0xf0, 0xcc,
+	0xf5, 0xa7, 0xc4, 0x23, 0x45, 0x3d, 0x5a, 0xa3, 0xfb, 0x20, 0xda, 0x9f, 0x79, 0xf6, 0x30, 0xb4,
+	0x47, 0xa6, 0xe5, 0xfb, 0xce, 0x85, 0x35, 0x61, 0xae, 0xa9, 0x73, 0x7a, 0x8b, 0x92, 0xe5, 0x3f,
+	0x17, 0xa0, 0x96, 0xbe, 0x39, 0xf6, 0xf3, 0x34, 0xb6, 0x8b, 0x7c, 0x23, 0x09, 0xb6, 0x02, 0xdb,
+	0xbf, 0x70, 0x86, 0xdc, 0x16, 0xbe, 0xc4, 0x17, 0x27, 0xee, 0x34, 0xe7, 0xce, 0x88, 0x85, 0xa6,
+	0x44, 0x08, 0x27, 0xce, 0x28, 0x65, 0xe4, 0x46, 0xc6, 0x48, 0x19, 0x4a, 0xae, 0x67, 0xfb, 0x56,
+	0xe8, 0xfa, 0x52, 0x91, 0xee, 0xe3, 0x6b, 0x74, 0x0f, 0xaa, 0xfc, 0x3b, 0xe9, 0xf1, 0x0a, 0x27,
+	0x12, 0x0f, 0x1c, 0x80, 0xe0, 0xfa, 0xce, 0xd8, 0x99, 0x25, 0x9d, 0x0e, 0x94, 0x44, 0x04, 0xee,
+	0x83, 0x88, 0x43, 0xe0, 0xcc, 0x12, 0x9e, 0x2c, 0x51, 0x37, 0x24, 0xe8, 0x44, 0x74, 0x0f, 0x36,
+	0x03, 0x77, 0xee, 0x0f, 0x6d, 0xa9, 0x4c, 0x04, 0xd8, 0x8a, 0xd0, 0x43, 0x2b, 0x9c, 0x07, 0x12,
+	0x30, 0x3a, 0x59, 0x21, 0x15, 0x76, 0xb3, 0x1e, 0x36, 0x31, 0xea, 0x24, 0x81, 0x88, 0xdd, 0xc8,
+	0xb8, 0xd9, 0x70, 0xa6, 0x36, 0x7a, 0x0a, 0x37, 0xa3, 0x3d, 0x11, 0xb8, 0xe8, 0xae, 0x0a, 0xd9,
+	0x15, 0xa9, 0x8c, 0x62, 0x41, 0xf6, 0xfd, 0x18, 0x8a, 0x41, 0xe8, 0x7a, 0x81, 0x54, 0x25, 0xf0,
+	0x3d, 0xb8, 0x04, 0xbe, 0x18, 0x5f, 0x3a, 0x95, 0x56, 0xfe, 0x54, 0x80, 0x7a, 0xa4, 0xe8, 0xc4,
+	0x23, 0xe9, 0xf2, 0x14, 0x36, 0xc2, 0x85, 0x47, 0x43, 0x5b, 0x53, 0x15, 0xae, 0x29, 0x23, 0xd6,
+	0xa0, 0x7f, 0x8c, 0x85, 0x67, 0xeb, 0x44, 0x1e, 0x3d, 0x84, 0xe2, 0x1b, 0xd7, 0xf2, 0x47, 0x24,
+	0xf8, 0x82, 0xba, 0xbb, 0xd2, 0x04, 0x9d, 0xca, 0xa0, 0xe7, 0x38, 0x15, 0x98, 0x42, 0x82, 0x88,
+	0xeb, 0xa6, 0x5c, 0xbc, 0x0d, 0xfd, 0x08, 0xea, 0x9e, 0x6f, 0x5f, 0x38, 0xee, 0x3c, 0x30, 0x59,
+	0x00, 0x28, 0x80, 0x6b, 0x9c, 0x3c, 0xa0, 0x81, 0x38, 0x82, 0xbb, 0x91, 0xe0, 0x3a, 0xef, 0x52,
+	0x74, 0xdd, 0xe6, 0x72, 0xda, 0x4a, 0x2f, 0x3f, 0x84, 0xed, 0x48, 0x51, 0x84, 0xd9, 0x4d, 0x82,
+	0x59, 0x91, 0x33, 0xfa, 0x8c, 0xae, 0xfc, 0x0a, 0x20, 0xf6, 0x11, 0x2a, 0x43, 0xf1, 0x79, 0xaf,
+	0xa5, 0xb7, 0xc5, 0xb7, 0x50, 0x1d, 0x84, 0xae, 0xf6, 0xca, 0x1c, 0x68, 0xfa, 0x69, 0xe7, 0x50,
+	0x13, 0x73, 0x68, 0x1b, 0xaa, 0x03, 0xa3, 0x65, 0x9c, 0x0c, 0xcc, 0xc3, 0x8f, 0x5a, 0xdd, 0x23,
+	0x4d, 0xcc, 0xa3, 0x1b, 0x50, 0xef, 0x1f, 0xb7, 0x8c, 0x17, 0x3d, 0xfd, 0x25, 0x27, 0x16, 0x50,
+	0x05, 0x4a, 0x6d, 0xad, 0xdf, 0xd2, 0x0d, 0xad, 0x2d, 0x6e, 0x28, 0x2d, 0xa8, 0x0d, 0x68, 0x7e,
+	0xf1, 0x92, 0x9b, 0x4a, 0xb3, 0x5c, 0x26, 0xcd, 0x78, 0x65, 0xcc, 0xc7, 0x95, 0x51, 0xf9, 0xfb,
+	0x26, 0xd4, 0x23, 0x1d, 0xac, 0x02, 0x5f, 0xaa, 0x64, 0x7d, 0x8a, 0xcb, 0x50, 0x3a, 0xb7, 0xad,
+	0x11, 0x29, 0x56, 0x2c, 0xc3, 0xf9, 0x1a, 0x57, 0x2a, 0xaa, 0x32, 0x15, 0x25, 0x21, 0xa4, 0x68,
+	0x24, 0x21, 0xfa, 0x9f, 0x4a, 0x74, 0xee, 0xaa, 0x72, 0xa2, 0x89, 0xc8, 0x50, 0x1a, 0x5a, 0xa1,
+	0x3d, 0x76, 0xfd, 0x05, 0x4b, 0xf3, 0x68, 0x8d, 0x3e, 0xe0, 0xc9, 0x27, 0x90, 0xe4, 0xbb, 0xc7,
+	0x81, 0x9c, 0x71, 0x2d, 0x5f, 0x27, 0x12, 0x50, 0xfe, 0x5b, 0x01, 0x84, 0x04, 0xf9, 0x3a, 0x75,
+	0xff, 0x00, 0xb7, 0x33, 0x6f, 0xe2, 0x0e, 0x93, 0x1d, 0x10, 0x28, 0x69, 0x65, 0x63, 0x28, 0xac,
+	0xec, 0x44, 0xf8, 0x7c, 0x93, 0x24, 0x3a, 0x0d, 0x47, 0x09, 0x13, 0x08, 0x54, 0x93, 0x05, 0x99,
+	0xc5, 0x82, 0xaf, 0xd1, 0x23, 0x40, 0x96, 0x33, 0xcd, 0x16, 0x34, 0x1a, 0x10, 0x91, 0x70, 0x92,
+	0xd5, 0xec, 0x09, 0xec, 0x50, 0xe9, 0x4c, 0xb2, 0xd1, 0xe8, 0x50, 0x4d, 0xe9, 0x0c, 0xfb, 0x21,
+	0xd4, 0xe9, 0x0e, 0xcf, 0x0a, 0x02, 0x2a, 0x4c, 0x83, 0x54, 0x25, 0xe4, 0xbe, 0x15, 0x04, 0x44,
+	0x6e, 0x6d, 0x6d, 0x2d, 0x7f, 0xab, 0xda, 0x0a, 0x97, 0xd5, 0xd6, 0x47, 0x80, 0xa2, 0x7d, 0xb1,
+	0x59, 0xb4, 0x88, 0x47, 0x3d, 0x34, 0xb2, 0x2c, 0xee, 0x06, 0x95, 0x64, 0x37, 0x50, 0xfe, 0x58,
+	0x04, 0x21, 0xaa, 0xbf, 0xa7, 0xea, 0x7f, 0x2a, 0xd2, 0x8f, 0xb3, 0x91, 0xae, 0xa9, 0x62, 0x84,
+	0x4f, 0x16, 0xf1, 0x6b, 0xc6, 0xfe, 0xa7, 0x50, 0x4d, 0xc5, 0x9e, 0x84, 0x5d, 0x50, 0xe5, 0x06,
+	0x9d, 0x0c, 0x1b, 0x7c, 0x32, 0x6c, 0x18, 0x7c, 0x74, 0xd4, 0x2b, 0x49, 0x48, 0xa0, 0x43, 0x1e,
+	0xdc, 0xb8, 0xf4, 0x6f, 0x5d, 0xa9, 0xa2, 0x96, 0x46, 0x09, 0xfa, 0x00, 0x20, 0x46, 0x88, 0x54,
+	0xba, 0x72, 0x7f, 0x39, 0x02, 0x0e, 0xd2, 0x56, 0x8c, 0x3c, 0xe5, 0x2b, 0x15, 0x64, 0xc7, 0x21,
+	0xd4, 0x49, 0xe0, 0x21, 0xbe, 0x09, 0x5c, 0xa9, 0x68, 0x7b, 0x09, 0x5e, 0xd8, 0xa5, 0x29, 0x68,
+	0x49, 0xc2, 0x95, 0x5a, 0x2a, 0x49, 0xc4, 0xa1, 0x26, 0x14, 0x47, 0xf6, 0xc4, 0x5a, 0x10, 0xb0,
+	0x09, 0xea, 0xfe, 0xd2, 0xc6, 0x36, 0x9b, 0xd2, 0x75, 0x2a, 0x87, 0x1e, 0x46, 0xf0, 0xac, 0x12,
+	0x30, 0xdc, 0x48, 0x75, 0x5d, 0x5a, 0x8d, 0x23, 0xcc, 0x7e, 0xb3, 0x01, 0x62, 0xba, 0xff, 0x9e,
+	0xaa, 0xdf, 0xe5, 0xe8, 0x57, 0xfe, 0x7f, 0x1b, 0xfd, 0x2e, 0xeb, 0x0a, 0xb1, 0xa7, 0x85, 0x2b,
+	0x3d, 0xbd, 0x2a, 0x35, 0x2a, 0xff, 0x76, 0x6a, 0xac, 0x06, 0x66, 0xf5, 0xdb, 0x00, 0x33, 0xc2,
+	0x55, 0xed, 0x9a, 0xb8, 0xba, 0xcf, 0x7b, 0x60, 0x9d, 0xf4, 0xc0, 0xec, 0x65, 0x71, 0xc9, 0xe3,
+	0x43, 0xe7, 0xd7, 0x79, 0xa8, 0xa7, 0x66, 0xbc, 0xeb, 0x55, 0xc3, 0x6b, 0xbc, 0x77, 0x52, 0x4f,
+	0xc3, 0x42, 0xe6, 0x69, 0x98, 0x7a, 0x7d, 0x6d, 0xa4, 0x5f, 0x5f, 0xe8, 0x43, 0xa8, 0xf8, 0x74,
+	0x8a, 0x8a, 0xc7, 0xc1, 0xcb, 0x9d, 0x26, 0x30, 0x79, 0x4c, 0x41, 0x3f, 0x49, 0x3d, 0x21, 0x37,
+	0x89, 0x0b, 0xa4, 0x94, 0x0b, 0x12, 0x19, 0x94, 0x7c, 0x36, 0x2a, 0xbf, 0x29, 0xc0, 0x76, 0x66,
+	0x4e, 0x38, 0x55, 0xbf, 0x8b, 0x21, 0xec, 0xe9, 0x8a, 0x21, 0x6c, 0x0d, 0x28, 0xbf, 0x3f, 0x93,
+	0xd9, 0xfd, 0xf4, 0x64, 0x76, 0x19, 0x2a, 0x8f, 0x60, 0x6b, 0x40, 0xa1, 0x84, 0xf6, 0xa1, 0x34,
+	0xf4, 0x83, 0x24, 0x10, 0xb7, 0x86, 0x7e, 0x70, 0x4d, 0x10, 0x2a, 0xbf, 0x80, 0x1b, 0xc7, 0x4e,
+	0x10, 0x32, 0x65, 0x41, 0x62, 0x38, 0xf7, 0xac, 0xb1, 0x6d, 0x06, 0xce, 0xe7, 0x54, 0x2b, 0x7e,
+	0xe7, 0x5a, 0x63, 0x7b, 0xe0, 0x7c, 0x6e, 0xa3, 0xdb, 0x00, 0x84, 0x19, 0xba, 0x1f, 0xdb, 0x33,
+	0xa6, 0x94, 0x88, 0x1b, 0x98, 0xa0, 0x7c, 0x9d, 0x83, 0x9d, 0xb4, 0x4e, 0x36, 0xac, 0x3f, 0x84,
+	0x12, 0x3b, 0x3a, 0x90, 0x72, 0xe4, 0x8a, 0xf5, 0xb8, 0xb9, 0x13, 0xba, 0x1e, 0x09, 0xe0, 0xd9,
+	0x6a, 0x66, 0x7f, 0x16, 0x9a, 0x4b, 0x27, 0x55, 0x31, 0xb9, 0xcf, 0x4f, 0xc3, 0xc6, 0x84, 0x6e,
+	0x68, 0x4d, 0xa8, 0xa9, 0xf4, 0x77, 0x83, 0x32, 0xa1, 0x60, 0x5b, 0x95, 0x06, 0x6c, 0x1f, 0xd9,
+	0xdc, 0x14, 0x7e, 0xbb, 0xf5, 0x2e, 0x53, 0x1c, 0xd8, 0x1d, 0xd8, 0x96, 0x3f, 0x3c, 0xcf, 0x7a,
+	0x64, 0x07, 0x8a, 0x9f, 0xcc, 0x6d, 0x7f, 0xc1, 0x36, 0xd0, 0x45, 0xda, 0x4f, 0xf9, 0x4b, 0xfd,
+	0x54, 0xc8, 0xfa, 0xe9, 0xb7, 0x39, 0xd8, 0xcb, 0x9e, 0xf5, 0xdf, 0xf3, 0xd4, 0x83, 0xbf, 0xe4,
+	0xa3, 0x91, 0x8f, 0xa4, 0x92, 0x04, 0x3b, 0x86, 0xde, 0xea, 0x74, 0x4d, 0xf6, 0xda, 0x3b, 0xe9,
+	0xfe, 0xbc, 0xdb, 0x7b, 0xd5, 0x15, 0xdf, 0x5a, 0xe2, 0xf4, 0xba, 0xa6, 0xd1, 0x79, 0x89, 0xdf,
+	0x86, 0x7b, 0x80, 0x52, 0x1c, 0xad, 0xa5, 0x1f, 0xbf, 0x16, 0xf3, 0x68, 0x17, 0xb6, 0x53, 0xf4,
+	0xe3, 0x96, 0x81, 0x9f, 0x88, 0x32, 0xec, 0xa5, 0xc8, 0x87, 0xad, 0xee, 0xa1, 0x76, 0x7c, 0x8c,
+	0x1f, 0x8c, 0x4b, 0xbc, 0x6e, 0xcf, 0xd4, 0xb5, 0x7e, 0x4f, 0x37, 0xc4, 0x22, 0x7a, 0x1b, 0xa4,
+	0x14, 0x6f, 0x60, 0xb4, 0x74, 0x63, 0x60, 0x7e, 0xa4, 0xe9, 0x9a, 0xb8, 0xb9, 0xb4, 0xb3, 0xf7,
+	0xe2, 0x85, 0xa9, 0xf7, 0x4e, 0x0c, 0x4d, 0xdc, 0x42, 0xef, 0xc0, 0xed, 0xf4, 0x89, 0xe4, 0xb5,
+	0x6a, 0xf6, 0x5e, 0x98, 0x3d, 0xbd, 0x73, 0xd4, 0xe9, 0x8a, 0x25, 0x74, 0x07, 0xe4, 0x94, 0x88,
+	0xae, 0x75, 0xba, 0xf8, 0x53, 0x7b, 0xa9, 0x75, 0x0d, 0xb1, 0xbc, 0x74, 0xfb, 0x96, 0xae, 0x77,
+	0x4e, 0xb5, 0xb6, 0x08, 0x68, 0x1f, 0x76, 0x53, 0x9c, 0xe8, 0xf9, 0x2b, 0x3c, 0xf8, 0x2a, 0x07,
+	0x25, 0x3e, 0xc0, 0x62, 0x6f, 0x0c, 0x8c, 0x5e, 0xdf, 0x34, 0x5e, 0xf7, 0xb5, 0x84, 0x5b, 0x77,
+	0x40, 0x8c, 0xc9, 0xcc, 0x9c, 0x1c, 0xbe, 0x4d, 0x4c, 0xed, 0x74, 0x0d, 0x4d, 0x7f, 0xa9, 0xb5,
+	0x3b, 0xd8, 0x7f, 0x79, 0x84, 0xa0, 0x16, 0xf3, 0xfa, 0xad, 0xc1, 0x40, 0x2c, 0x60, 0x23, 0x62,
+	0x5a, 0x5b, 0x1b, 0x18, 0x9d, 0x6e, 0xcb, 0xe8, 0xf4, 0xba, 0xe2, 0x86, 0xfa, 0x8f, 0x22, 0xfb,
+	0xd5, 0x93, 0x95, 0x70, 0x74, 0x0c, 0xe5, 0x23, 0x3b, 0x24, 0xa4, 0x00, 0xed, 0x64, 0x7e, 0xd1,
+	0x20, 0xb0, 0x97, 0x57, 0xff, 0x30, 0xa2, 0xa0, 0xaf, 0xbe, 0xf9, 0xeb, 0xef, 0xf3, 0x15, 0x04,
+	0xcd, 0x8b, 0xf7, 0x9a, 0x54, 0x02, 0xfd, 0x12, 0xea, 0xaf, 0xac, 0x70, 0x78, 0xde, 0x8e, 0x7f,
+	0x66, 0x5c, 0xad, 0xf3, 0xe6, 0x9a, 0x5f, 0x69, 0x14, 0x89, 0x68, 0x45, 0x48, 0x8c, 0xb5, 0x3e,
+	0xfb, 0x14, 0xab, 0x7c, 0x92, 0x43, 0x26, 0x54, 0x92, 0x45, 0x05, 0xdd, 0xe2, 0x4a, 0x56, 0x94,
+	0x2f, 0xf9, 0xed, 0xd5, 0x4c, 0x66, 0xfc, 0x0e, 0x39, 0xa6, 0x86, 0x2a, 0xf8, 0x98, 0x28, 0x8d,
+	0x5e, 0x03, 0xc4, 0x95, 0x02, 0xed, 0x73, 0x0d, 0x4b, 0xd5, 0x43, 0xce, 0xa6, 0xa2, 0x72, 0x40,
+	0xf4, 0xed, 0xa3, 0x9b, 0x49, 0x7d, 0xcd, 0x2f, 0x78, 0x89, 0xf9, 0x12, 0x4d, 0xa0, 0x96, 0x4e,
+	0x74, 0x74, 0x3b, 0xd2, 0xb1, 0xaa, 0xd8, 0xc8, 0x77, 0xd6, 0xb1, 0xd9, 0x0d, 0x6e, 0x91, 0x13,
+	0x77, 0xd1, 0x8d, 0xe4, 0x89, 0xcf, 0x02, 0x22, 0x8c, 0x4c, 0x7a, 0x11, 0x16, 0xe3, 0xbd, 0xa5,
+	0xf7, 0x7d, 0x26, 0x08, 0x99, 0x7e, 0xae, 0xdc, 0x25, 0xba, 0x65, 0x24, 0x11, 0xdd, 0x94, 0x19,
+	0x34, 0xbf, 0x88, 0x3a, 0xfc, 0x97, 0xa8, 0x0f, 0x42, 0x04, 0x9b, 0x53, 0xf5, 0xaa, 0x20, 0x67,
+	0x86, 0xa7, 0x24, 0x74, 0x54, 0x0e, 0x9d, 0x21, 0x54, 0x62, 0x93, 0x4f, 0xd5, 0xb5, 0x46, 0xef,
+	0xaf, 0x31, 0xfa, 0x54, 0x4d, 0x9a, 0xad, 0xae, 0x34, 0xfb, 0xf9, 0xe2, 0x77, 0xad, 0x0b, 0x74,
+	0x0c, 0xec, 0xdf, 0x14, 0xca, 0x87, 0xfc, 0x0b, 0xdd, 0x3b, 0x0f, 0x43, 0x2f, 0x78, 0xd6, 0x6c,
+	0x8e, 0x9d, 0xf0, 0x7c, 0xfe, 0xa6, 0x31, 0x74, 0xa7, 0xcd, 0xa9, 0x35, 0x99, 0x3a, 0xb3, 0x73,
+	0x2b, 0x60, 0x26, 0xca, 0xb5, 0xa9, 0x35, 0xf9, 0x19, 0xa6, 0x36, 0x86, 0x6e, 0x63, 0xfe, 0xb1,
+	0x5a, 0x78, 0xaf, 0xf1, 0xe4, 0x41, 0x3e, 0x97, 0x57, 0x45, 0xcb, 0xf3, 0x26, 0xce, 0x90, 0xc4,
+	0xa0, 0xf9, 0xeb, 0xc0, 0x9d, 0x3d, 0x5b, 0xa2, 0xbc, 0xd9, 0x24, 0x23, 0xd9, 0xfb, 0xff, 0x1a,
+	0x00, 0x3b, 0xd5, 0x50, 0x81, 0x31, 0x19, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// TrainServiceClient is the client API for TrainService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TrainServiceClient interface {
+	GetTrains(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponse, error)
+	WatchDepartures(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (TrainService_WatchDeparturesClient, error)
+	ListStations(ctx context.Context, in *ListStationsRequest, opts ...grpc.CallOption) (*ListStationsResponse, error)
+	GetStation(ctx context.Context, in *GetStationRequest, opts ...grpc.CallOption) (*Station, error)
+	SearchStations(ctx context.Context, in *SearchStationsRequest, opts ...grpc.CallOption) (*SearchStationsResponse, error)
+	GetService(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*ServiceResponse, error)
+	GetTrainsV2(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponseV2, error)
+	GetServiceV2(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*ServiceResponseV2, error)
+}
+
+type trainServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTrainServiceClient(cc *grpc.ClientConn) TrainServiceClient {
+	return &trainServiceClient{cc}
+}
+
+func (c *trainServiceClient) GetTrains(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponse, error) {
+	out := new(TrainResponse)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/GetTrains", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) WatchDepartures(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (TrainService_WatchDeparturesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TrainService_serviceDesc.Streams[0], "/trains.TrainService/WatchDepartures", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &trainServiceWatchDeparturesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TrainService_WatchDeparturesClient interface {
+	Recv() (*DepartureUpdate, error)
+	grpc.ClientStream
+}
+
+type trainServiceWatchDeparturesClient struct {
+	grpc.ClientStream
+}
+
+func (x *trainServiceWatchDeparturesClient) Recv() (*DepartureUpdate, error) {
+	m := new(DepartureUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *trainServiceClient) ListStations(ctx context.Context, in *ListStationsRequest, opts ...grpc.CallOption) (*ListStationsResponse, error) {
+	out := new(ListStationsResponse)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/ListStations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) GetStation(ctx context.Context, in *GetStationRequest, opts ...grpc.CallOption) (*Station, error) {
+	out := new(Station)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/GetStation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) SearchStations(ctx context.Context, in *SearchStationsRequest, opts ...grpc.CallOption) (*SearchStationsResponse, error) {
+	out := new(SearchStationsResponse)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/SearchStations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) GetService(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*ServiceResponse, error) {
+	out := new(ServiceResponse)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/GetService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) GetTrainsV2(ctx context.Context, in *TrainRequest, opts ...grpc.CallOption) (*TrainResponseV2, error) {
+	out := new(TrainResponseV2)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/GetTrainsV2", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trainServiceClient) GetServiceV2(ctx context.Context, in *ServiceRequest, opts ...grpc.CallOption) (*ServiceResponseV2, error) {
+	out := new(ServiceResponseV2)
+	err := c.cc.Invoke(ctx, "/trains.TrainService/GetServiceV2", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TrainServiceServer is the server API for TrainService service.
+type TrainServiceServer interface {
+	GetTrains(context.Context, *TrainRequest) (*TrainResponse, error)
+	WatchDepartures(*TrainRequest, TrainService_WatchDeparturesServer) error
+	ListStations(context.Context, *ListStationsRequest) (*ListStationsResponse, error)
+	GetStation(context.Context, *GetStationRequest) (*Station, error)
+	SearchStations(context.Context, *SearchStationsRequest) (*SearchStationsResponse, error)
+	GetService(context.Context, *ServiceRequest) (*ServiceResponse, error)
+	GetTrainsV2(context.Context, *TrainRequest) (*TrainResponseV2, error)
+	GetServiceV2(context.Context, *ServiceRequest) (*ServiceResponseV2, error)
+}
+
+// UnimplementedTrainServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedTrainServiceServer struct {
+}
+
+func (*UnimplementedTrainServiceServer) GetTrains(ctx context.Context, req *TrainRequest) (*TrainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrains not implemented")
+}
+func (*UnimplementedTrainServiceServer) WatchDepartures(req *TrainRequest, srv TrainService_WatchDeparturesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDepartures not implemented")
+}
+func (*UnimplementedTrainServiceServer) ListStations(ctx context.Context, req *ListStationsRequest) (*ListStationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStations not implemented")
+}
+func (*UnimplementedTrainServiceServer) GetStation(ctx context.Context, req *GetStationRequest) (*Station, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStation not implemented")
+}
+func (*UnimplementedTrainServiceServer) SearchStations(ctx context.Context, req *SearchStationsRequest) (*SearchStationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchStations not implemented")
+}
+func (*UnimplementedTrainServiceServer) GetService(ctx context.Context, req *ServiceRequest) (*ServiceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetService not implemented")
+}
+func (*UnimplementedTrainServiceServer) GetTrainsV2(ctx context.Context, req *TrainRequest) (*TrainResponseV2, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrainsV2 not implemented")
+}
+func (*UnimplementedTrainServiceServer) GetServiceV2(ctx context.Context, req *ServiceRequest) (*ServiceResponseV2, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServiceV2 not implemented")
+}
+
+func RegisterTrainServiceServer(s *grpc.Server, srv TrainServiceServer) {
+	s.RegisterService(&_TrainService_serviceDesc, srv)
+}
+
+func _TrainService_GetTrains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).GetTrains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/GetTrains",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).GetTrains(ctx, req.(*TrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_WatchDepartures_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TrainRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TrainServiceServer).WatchDepartures(m, &trainServiceWatchDeparturesServer{stream})
+}
+
+type TrainService_WatchDeparturesServer interface {
+	Send(*DepartureUpdate) error
+	grpc.ServerStream
+}
+
+type trainServiceWatchDeparturesServer struct {
+	grpc.ServerStream
+}
+
+func (x *trainServiceWatchDeparturesServer) Send(m *DepartureUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TrainService_ListStations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).ListStations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/ListStations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).ListStations(ctx, req.(*ListStationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_GetStation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).GetStation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/GetStation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).GetStation(ctx, req.(*GetStationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_SearchStations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchStationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).SearchStations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/SearchStations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).SearchStations(ctx, req.(*SearchStationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_GetService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).GetService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/GetService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).GetService(ctx, req.(*ServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_GetTrainsV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).GetTrainsV2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/GetTrainsV2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).GetTrainsV2(ctx, req.(*TrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrainService_GetServiceV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrainServiceServer).GetServiceV2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trains.TrainService/GetServiceV2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrainServiceServer).GetServiceV2(ctx, req.(*ServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _TrainService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "trains.TrainService",
+	HandlerType: (*TrainServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTrains",
+			Handler:    _TrainService_GetTrains_Handler,
+		},
+		{
+			MethodName: "ListStations",
+			Handler:    _TrainService_ListStations_Handler,
+		},
+		{
+			MethodName: "GetStation",
+			Handler:    _TrainService_GetStation_Handler,
+		},
+		{
+			MethodName: "SearchStations",
+			Handler:    _TrainService_SearchStations_Handler,
+		},
+		{
+			MethodName: "GetService",
+			Handler:    _TrainService_GetService_Handler,
+		},
+		{
+			MethodName: "GetTrainsV2",
+			Handler:    _TrainService_GetTrainsV2_Handler,
+		},
+		{
+			MethodName: "GetServiceV2",
+			Handler:    _TrainService_GetServiceV2_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDepartures",
+			Handler:       _TrainService_WatchDepartures_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "trains.proto",
 }
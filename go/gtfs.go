@@ -0,0 +1,382 @@
+/*
+ gtfs.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Export of the trains on a set of routes as a GTFS static feed for tools that
+speak GTFS rather than transportAPI.  Routes are given as for the record command.
+Each train on the live board for a route has its service timetable fetched and
+becomes a GTFS trip running on that day, identified by train_uid and date.  The
+feed is zipped with these files:
+  agency.txt          one agency per operator, from operator and operator_name
+  stops.txt           a station for each station called at, named from
+                      station_codes.csv, with a stop per platform used as its
+                      child carrying its platform_code
+  routes.txt          one rail route per operator, origin and destination
+  trips.txt           one trip per train per day
+  stop_times.txt      scheduled arrival and departure at each call
+  calendar_dates.txt  the days the trips run, which GTFS needs to be valid
+GTFS needs a location for every stop and station_codes.csv doesn't have one, so
+each station is looked up once using transportAPI's places endpoint.  Trains
+calling at a station that can't be found are logged and left out so that the
+feed stays valid.  A call whose platform isn't known uses a stop for the station
+as a whole, with the CRS code as its stop_id.  Times after midnight
+are written as 24:00 onwards as GTFS expects.
+
+Installation
+------------
+$ go run . export gtfs OXF:PAD PAD:OXF --out=trains_gtfs.zip
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	grequests "github.com/levigross/grequests"
+)
+
+const GTFS_FILE = "trains_gtfs.zip"
+const GTFS_TIMEZONE = "Europe/London"
+
+// GTFS needs a URL for every agency and transportAPI doesn't give one
+const GTFS_AGENCY_URL = "https://www.nationalrail.co.uk/"
+
+// GTFS route_type for rail
+const GTFS_RAIL = 2
+
+type StationLocation struct {
+	Name        string  `json:"name"`
+	StationCode string  `json:"station_code"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+type StationPlaces struct {
+	Member []StationLocation `json:"member"`
+}
+
+// gtfsStop is a platform at a station, or the station as a whole when the platform
+// isn't known, that trips in the feed call at
+type gtfsStop struct {
+	id       string
+	code     string
+	platform string
+}
+
+// gtfsTrip is one train on one day along with the stops it calls at
+type gtfsTrip struct {
+	id        string
+	row       []string
+	stopTimes [][]string
+	stops     []gtfsStop
+}
+
+// gtfsFeed gathers the rows of each file in the feed
+type gtfsFeed struct {
+	agencies  map[string]string
+	routes    map[string][]string
+	trips     []gtfsTrip
+	seen      map[string]bool
+	locations map[string]*StationLocation
+}
+
+func newGTFSFeed() *gtfsFeed {
+	return &gtfsFeed{
+		agencies:  make(map[string]string),
+		routes:    make(map[string][]string),
+		seen:      make(map[string]bool),
+		locations: make(map[string]*StationLocation),
+	}
+}
+
+// gtfsTime converts "HH:MM" to "HH:MM:SS", carrying on past 24:00 for a train that
+// runs through midnight so that times never go backwards within a trip
+func gtfsTime(clock string, previous int) (string, int, bool) {
+	mins, ok := clockMinutes(clock)
+	if !ok {
+		return "", previous, false
+	}
+	mins += (previous / MINUTES_PER_DAY) * MINUTES_PER_DAY
+	if mins < previous {
+		mins += MINUTES_PER_DAY
+	}
+	return fmt.Sprintf("%02d:%02d:00", mins/60, mins%60), mins, true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+func gtfsStopId(code string, platform string) string {
+	if len(platform) == 0 {
+		return code
+	}
+	return code + ":" + platform
+}
+
+// gtfsStationId is the parent station of the stops at a station
+func gtfsStationId(code string) string {
+	return "station:" + code
+}
+
+// addTrip adds a train and the stations it calls at to the feed, once per day it runs.
+// The date of the board it was on is used if the trip doesn't say which day that is.
+func (feed *gtfsFeed) addTrip(trip TrainTrip, date string) {
+	var calls []TrainStop
+	for _, stop := range trip.Stops {
+		if !stop.IsPass() && len(stop.StationCode) > 0 && len(scheduledTime(stop)) > 0 {
+			calls = append(calls, stop)
+		}
+	}
+	date = serviceDate(trip, date)
+	tripId := trip.TrainUid + "_" + date
+	if len(calls) < 2 || feed.seen[tripId] {
+		return
+	}
+	feed.seen[tripId] = true
+	serviceId := strings.Replace(date, "-", "", -1)
+
+	agencyId := trip.Operator
+	if _, ok := feed.agencies[agencyId]; !ok || len(trip.OperatorName) > 0 {
+		feed.agencies[agencyId] = firstNonEmpty(trip.OperatorName, trip.Operator)
+	}
+	first, last := calls[0], calls[len(calls)-1]
+	routeId := fmt.Sprintf("%s:%s-%s", agencyId, first.StationCode, last.StationCode)
+	feed.routes[routeId] = []string{routeId, agencyId, "", fmt.Sprintf("%s to %s", first.StationName, last.StationName), strconv.Itoa(GTFS_RAIL)}
+	gtrip := gtfsTrip{id: tripId, row: []string{routeId, serviceId, tripId, last.StationName, trip.TrainUid}}
+
+	previous := 0
+	for i, stop := range calls {
+		arrival := firstNonEmpty(stop.AimedArrival, stop.AimedDeparture)
+		departure := firstNonEmpty(stop.AimedDeparture, stop.AimedArrival)
+		arr, mins, _ := gtfsTime(arrival, previous)
+		dep, mins, _ := gtfsTime(departure, mins)
+		previous = mins
+		stopId := gtfsStopId(stop.StationCode, stop.Platform)
+		gtrip.stops = append(gtrip.stops, gtfsStop{id: stopId, code: stop.StationCode, platform: stop.Platform})
+		gtrip.stopTimes = append(gtrip.stopTimes, []string{tripId, arr, dep, stopId, strconv.Itoa(i + 1)})
+	}
+	feed.trips = append(feed.trips, gtrip)
+}
+
+// fetchStationLocation finds where a station is using transportAPI's places search
+func fetchStationLocation(crs_code string) (StationLocation, error) {
+	params := make(map[string]string)
+	params["app_id"] = APP_ID
+	params["app_key"] = APP_KEY
+	params["query"] = crs_code
+	params["type"] = "train_station"
+	resp, err := grequests.Get("http://transportapi.com/v3/uk/places.json", &grequests.RequestOptions{Params: params})
+	if err != nil {
+		return StationLocation{}, fmt.Errorf("Unable to make places request: %v", err)
+	}
+	respStr := resp.String()
+	debugResponse(resp, respStr)
+	if !resp.Ok {
+		return StationLocation{}, fmt.Errorf("Places request failed with status %d", resp.StatusCode)
+	}
+	places := &StationPlaces{}
+	if err := resp.JSON(places); err != nil {
+		return StationLocation{}, fmt.Errorf("Cannot serialize JSON: %v", err)
+	}
+	for _, place := range places.Member {
+		if place.StationCode == crs_code {
+			return place, nil
+		}
+	}
+	return StationLocation{}, fmt.Errorf("No location found for %s", crs_code)
+}
+
+// locate finds every station called at, once each, using transportAPI's places search
+func (feed *gtfsFeed) locate(find func(crs_code string) (StationLocation, error)) {
+	for _, trip := range feed.trips {
+		for _, stop := range trip.stops {
+			if _, ok := feed.locations[stop.code]; ok {
+				continue
+			}
+			location, err := find(stop.code)
+			if err != nil {
+				slog.Warn("Cannot locate station", "station_code", stop.code, "error", err)
+				feed.locations[stop.code] = nil
+				continue
+			}
+			feed.locations[stop.code] = &location
+		}
+	}
+}
+
+// locatedTrips leaves out trips calling at a station we couldn't locate, as GTFS
+// needs a location for every stop
+func (feed *gtfsFeed) locatedTrips() []gtfsTrip {
+	var trips []gtfsTrip
+	for _, trip := range feed.trips {
+		located := true
+		for _, stop := range trip.stops {
+			if feed.locations[stop.code] == nil {
+				slog.Warn("Trip left out as a station it calls at has no location", "trip_id", trip.id, "station_code", stop.code)
+				located = false
+				break
+			}
+		}
+		if located {
+			trips = append(trips, trip)
+		}
+	}
+	return trips
+}
+
+// stopRows writes a station for each station called at, named from station_codes.csv,
+// with a stop for each platform used as its child
+func (feed *gtfsFeed) stopRows(trips []gtfsTrip) [][]string {
+	stations := csvToJSONMap(STATION_NAMES_CSV)
+	stops := make(map[string]gtfsStop)
+	for _, trip := range trips {
+		for _, stop := range trip.stops {
+			stops[stop.id] = stop
+			stops[gtfsStationId(stop.code)] = gtfsStop{id: gtfsStationId(stop.code), code: stop.code}
+		}
+	}
+	var rows [][]string
+	for _, stop := range stops {
+		location := feed.locations[stop.code]
+		lat := strconv.FormatFloat(location.Latitude, 'f', 6, 64)
+		lon := strconv.FormatFloat(location.Longitude, 'f', 6, 64)
+		name := firstNonEmpty(stations[stop.code], location.Name, stop.code)
+		if stop.id == gtfsStationId(stop.code) {
+			rows = append(rows, []string{stop.id, stop.code, name, lat, lon, "1", "", ""})
+			continue
+		}
+		rows = append(rows, []string{stop.id, stop.code, name, lat, lon, "0", gtfsStationId(stop.code), stop.platform})
+	}
+	return rows
+}
+
+func sortedRows(rows [][]string) [][]string {
+	sort.SliceStable(rows, func(i, j int) bool { return strings.Join(rows[i], ",") < strings.Join(rows[j], ",") })
+	return rows
+}
+
+func writeGTFSFile(z *zip.Writer, name string, header []string, rows [][]string) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+// write zips the trips we could locate into out_file, returning how many trips and
+// stops were written
+func (feed *gtfsFeed) write(out_file string) (int, int, error) {
+	trips := feed.locatedTrips()
+	if len(trips) == 0 {
+		return 0, 0, fmt.Errorf("no trains calling only at stations with a location")
+	}
+	var agencies, routes, dates, tripRows, stopTimes [][]string
+	usedAgencies := make(map[string]bool)
+	usedRoutes := make(map[string]bool)
+	usedDates := make(map[string]bool)
+	for _, trip := range trips {
+		route := feed.routes[trip.row[0]]
+		if !usedRoutes[route[0]] {
+			usedRoutes[route[0]] = true
+			routes = append(routes, route)
+		}
+		if agencyId := route[1]; !usedAgencies[agencyId] {
+			usedAgencies[agencyId] = true
+			agencies = append(agencies, []string{agencyId, feed.agencies[agencyId], GTFS_AGENCY_URL, GTFS_TIMEZONE})
+		}
+		if serviceId := trip.row[1]; !usedDates[serviceId] {
+			usedDates[serviceId] = true
+			dates = append(dates, []string{serviceId, serviceId, "1"})
+		}
+		tripRows = append(tripRows, trip.row)
+		stopTimes = append(stopTimes, trip.stopTimes...)
+	}
+	stops := feed.stopRows(trips)
+
+	f, err := os.Create(out_file)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	z := zip.NewWriter(f)
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"agency.txt", []string{"agency_id", "agency_name", "agency_url", "agency_timezone"}, sortedRows(agencies)},
+		{"stops.txt", []string{"stop_id", "stop_code", "stop_name", "stop_lat", "stop_lon", "location_type", "parent_station", "platform_code"}, sortedRows(stops)},
+		{"routes.txt", []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type"}, sortedRows(routes)},
+		{"trips.txt", []string{"route_id", "service_id", "trip_id", "trip_headsign", "trip_short_name"}, tripRows},
+		{"stop_times.txt", []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"}, stopTimes},
+		{"calendar_dates.txt", []string{"service_id", "date", "exception_type"}, sortedRows(dates)},
+	}
+	for _, file := range files {
+		if err := writeGTFSFile(z, file.name, file.header, file.rows); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return 0, 0, err
+	}
+	return len(trips), len(stops), f.Close()
+}
+
+func exportGTFS(routes []Route, out_file string) {
+	feed := newGTFSFeed()
+	for _, route := range routes {
+		journey, trips, err := fetchTrips(route)
+		if err != nil {
+			slog.Warn("Cannot fetch trains, leaving the route out", "route", string(route.Bucket()), "error", err)
+			continue
+		}
+		date := journey.Date
+		if len(date) == 0 {
+			date = time.Now().Format(DATE_FORMAT)
+		}
+		for _, trip := range trips {
+			feed.addTrip(trip, date)
+		}
+		fmt.Println(fmt.Sprintf("Fetched %d trains from %s to %s", len(trips), route.FromName, route.ToName))
+	}
+	if len(feed.trips) == 0 {
+		log.Fatal("No trains to export")
+	}
+	feed.locate(fetchStationLocation)
+	trips, stops, err := feed.write(out_file)
+	if err != nil {
+		log.Fatalf("Cannot write %s: %v", out_file, err)
+	}
+	if skipped := len(feed.trips) - trips; skipped > 0 {
+		fmt.Println(fmt.Sprintf("Left out %d trips calling at stations without a location", skipped))
+	}
+	fmt.Println(fmt.Sprintf("Wrote %d trips calling at %d stops to %s", trips, stops, out_file))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestGTFSTime(t *testing.T) {
+	tests := []struct {
+		clock    string
+		previous int
+		want     string
+		wantMins int
+		wantOk   bool
+	}{
+		{"10:16", 0, "10:16:00", 616, true},
+		{"10:16", 616, "10:16:00", 616, true},
+		{"23:59", 600, "23:59:00", 1439, true},
+		{"00:05", 1439, "24:05:00", 1445, true},
+		{"01:30", 1445, "25:30:00", 1530, true},
+		{"", 600, "", 600, false},
+		{"25:00", 600, "", 600, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s after %d", tt.clock, tt.previous), func(t *testing.T) {
+			got, mins, ok := gtfsTime(tt.clock, tt.previous)
+			if got != tt.want || mins != tt.wantMins || ok != tt.wantOk {
+				t.Errorf("gtfsTime(%q, %d) = %q, %d, %v, want %q, %d, %v", tt.clock, tt.previous, got, mins, ok, tt.want, tt.wantMins, tt.wantOk)
+			}
+		})
+	}
+}
+
+func testTrip(uid string, stops ...TrainStop) TrainTrip {
+	trip := TrainTrip{Stops: stops}
+	trip.TrainUid = uid
+	trip.Operator = "GW"
+	trip.OperatorName = "Great Western Railway"
+	return trip
+}
+
+func readGTFSFile(t *testing.T, zip_file string, name string) map[string][]string {
+	t.Helper()
+	z, err := zip.OpenReader(zip_file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := make(map[string][]string)
+		for _, record := range records[1:] {
+			rows[record[0]] = record
+		}
+		return rows
+	}
+	t.Fatalf("%s not in %s", name, zip_file)
+	return nil
+}
+
+func TestGTFSFeedWrite(t *testing.T) {
+	feed := newGTFSFeed()
+	feed.addTrip(testTrip("C1",
+		TrainStop{StationCode: "OXF", StationName: "Oxford", Platform: "4", AimedDeparture: "23:50"},
+		TrainStop{StationCode: "DID", StationName: "Didcot Parkway", AimedPass: "23:58"},
+		TrainStop{StationCode: "RDG", StationName: "Reading", AimedArrival: "00:10", AimedDeparture: "00:12"},
+		TrainStop{StationCode: "PAD", StationName: "London Paddington", Platform: "9", AimedArrival: "00:40"}), "2019-10-26")
+	// Already added for the day
+	feed.addTrip(testTrip("C1",
+		TrainStop{StationCode: "OXF", AimedDeparture: "23:50"},
+		TrainStop{StationCode: "PAD", AimedArrival: "00:40"}), "2019-10-26")
+	feed.addTrip(testTrip("C2",
+		TrainStop{StationCode: "OXF", StationName: "Oxford", Platform: "3", AimedDeparture: "10:00"},
+		TrainStop{StationCode: "XYZ", StationName: "Nowhere", AimedArrival: "10:30"}), "2019-10-26")
+	// On a board fetched before midnight but running tomorrow
+	tomorrow := testTrip("C3",
+		TrainStop{StationCode: "OXF", StationName: "Oxford", Platform: "4", AimedDeparture: "00:05"},
+		TrainStop{StationCode: "PAD", StationName: "London Paddington", Platform: "9", AimedArrival: "00:55"})
+	tomorrow.ServiceDate = "2019-10-27"
+	feed.addTrip(tomorrow, "2019-10-26")
+	if len(feed.trips) != 3 {
+		t.Fatalf("got %d trips, want 3", len(feed.trips))
+	}
+
+	feed.locate(func(crs_code string) (StationLocation, error) {
+		if crs_code == "XYZ" {
+			return StationLocation{}, fmt.Errorf("No location found for %s", crs_code)
+		}
+		return StationLocation{Name: crs_code, StationCode: crs_code, Latitude: 51.5, Longitude: -1.2}, nil
+	})
+	out := filepath.Join(t.TempDir(), "gtfs.zip")
+	trips, stops, err := feed.write(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// C2 calls at XYZ which has no location.  OXF:4, RDG and PAD:9 plus their stations.
+	if trips != 2 || stops != 6 {
+		t.Errorf("wrote %d trips and %d stops, want 2 and 6", trips, stops)
+	}
+	stopTimes := readGTFSFile(t, out, "stop_times.txt")
+	for _, tripId := range []string{"C1_2019-10-26", "C3_2019-10-27"} {
+		if _, ok := stopTimes[tripId]; !ok {
+			t.Errorf("no stop times for %s", tripId)
+		}
+	}
+	if _, ok := stopTimes["C3_2019-10-26"]; ok {
+		t.Errorf("C3 written for the board date rather than the day it runs")
+	}
+	if row, ok := readGTFSFile(t, out, "calendar_dates.txt")["20191027"]; !ok || row[1] != "20191027" {
+		t.Errorf("calendar dates for 20191027 = %v", row)
+	}
+
+	stopRows := readGTFSFile(t, out, "stops.txt")
+	tests := []struct {
+		stopId       string
+		locationType string
+		parent       string
+		platform     string
+	}{
+		{"station:OXF", "1", "", ""},
+		{"OXF:4", "0", "station:OXF", "4"},
+		{"station:RDG", "1", "", ""},
+		{"RDG", "0", "station:RDG", ""},
+		{"PAD:9", "0", "station:PAD", "9"},
+	}
+	for _, tt := range tests {
+		row, ok := stopRows[tt.stopId]
+		if !ok {
+			t.Errorf("no stop %s", tt.stopId)
+			continue
+		}
+		if row[3] == "" || row[4] == "" {
+			t.Errorf("stop %s has no location", tt.stopId)
+		}
+		if row[5] != tt.locationType || row[6] != tt.parent || row[7] != tt.platform {
+			t.Errorf("stop %s = %v, want location_type %s parent %q platform %q", tt.stopId, row, tt.locationType, tt.parent, tt.platform)
+		}
+	}
+	if _, ok := stopRows["XYZ"]; ok {
+		t.Errorf("stop XYZ written without a location")
+	}
+	if _, ok := readGTFSFile(t, out, "trips.txt")["GW:OXF-XYZ"]; ok {
+		t.Errorf("trip calling at XYZ written")
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ journey.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Utility module to work out journey timings for a train between two stations.
+The live departures board only tells us when a train leaves the origin station.
+To find out when it actually reaches the destination we walk the stops returned
+by the service timetable and pick out the origin and destination calling points.
+Times are returned by transportAPI as "HH:MM" strings so journeys crossing
+midnight are handled by wrapping the duration around 24 hours.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const MINUTES_PER_DAY = 24 * 60
+
+type TrainTrip struct {
+	TrainDeparture
+	Stops              []TrainStop `json:"stops"`
+	ServiceDate        string      `json:"service_date,omitempty"`
+	FromCode           string      `json:"from_code"`
+	ToCode             string      `json:"to_code"`
+	OriginDeparture    string      `json:"origin_departure_time"`
+	DestinationArrival string      `json:"destination_arrival_time"`
+	DurationMins       int         `json:"duration_mins"`
+	IntermediateStops  int         `json:"intermediate_stops"`
+}
+
+// clockMinutes converts an "HH:MM" time into minutes past midnight
+func clockMinutes(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// minutesBetween returns the minutes from one "HH:MM" time to a later one
+func minutesBetween(from string, to string) (int, bool) {
+	start, ok := clockMinutes(from)
+	if !ok {
+		return 0, false
+	}
+	end, ok := clockMinutes(to)
+	if !ok {
+		return 0, false
+	}
+	mins := end - start
+	if mins < 0 {
+		mins += MINUTES_PER_DAY
+	}
+	return mins, true
+}
+
+// lateness returns the minutes from an aimed to an actual "HH:MM" time, negative
+// if early, allowing for either side of midnight
+func lateness(aimed string, actual string) (int, bool) {
+	mins, ok := minutesBetween(aimed, actual)
+	if !ok {
+		return 0, false
+	}
+	if mins >= MINUTES_PER_DAY/2 {
+		mins -= MINUTES_PER_DAY
+	}
+	return mins, true
+}
+
+func stopDeparture(stop TrainStop) string {
+	if len(stop.ExpectedDeparture) > 0 {
+		return stop.ExpectedDeparture
+	}
+	return stop.AimedDeparture
+}
+
+func stopArrival(stop TrainStop) string {
+	if len(stop.ExpectedArrival) > 0 {
+		return stop.ExpectedArrival
+	}
+	return stop.AimedArrival
+}
+
+// IsPass reports whether the train runs through this timing point without calling
+func (stop TrainStop) IsPass() bool {
+	return len(stop.AimedPass) > 0 && len(stop.AimedArrival) == 0 && len(stop.AimedDeparture) == 0
+}
+
+// DelayMins returns how many minutes late (or early if negative) the train is at
+// this stop, comparing expected with aimed departure, arrival or pass time.
+func (stop TrainStop) DelayMins() (int, bool) {
+	aimed, expected := stop.AimedDeparture, stop.ExpectedDeparture
+	if len(aimed) == 0 || len(expected) == 0 {
+		aimed, expected = stop.AimedArrival, stop.ExpectedArrival
+	}
+	if len(aimed) == 0 || len(expected) == 0 {
+		aimed, expected = stop.AimedPass, stop.ExpectedPass
+	}
+	return lateness(aimed, expected)
+}
+
+func newTrainTrip(train TrainDeparture, stops []TrainStop, journey TrainJourney) TrainTrip {
+	trip := TrainTrip{TrainDeparture: train, Stops: stops, FromCode: journey.StationCode, ToCode: journey.DestinationCode}
+	origin := -1
+	dest := -1
+	for i := 0; i < len(stops); i++ {
+		stationCode := stops[i].StationCode
+		if origin < 0 && stationCode == journey.StationCode {
+			origin = i
+		} else if origin >= 0 && stationCode == journey.DestinationCode {
+			dest = i
+			break
+		}
+	}
+	trip.OriginDeparture = train.ExpectedDeparture
+	if len(trip.OriginDeparture) == 0 {
+		trip.OriginDeparture = train.AimedDeparture
+	}
+	if origin >= 0 && len(stopDeparture(stops[origin])) > 0 {
+		trip.OriginDeparture = stopDeparture(stops[origin])
+	}
+	if dest < 0 {
+		return trip
+	}
+	trip.DestinationArrival = stopArrival(stops[dest])
+	for i := origin + 1; i < dest; i++ {
+		if !stops[i].IsPass() {
+			trip.IntermediateStops++
+		}
+	}
+	trip.DurationMins, _ = minutesBetween(trip.OriginDeparture, trip.DestinationArrival)
+	return trip
+}
+
+func getTrainTrips(journey TrainJourney) []TrainTrip {
+	var trips []TrainTrip
+	slog.Debug("All departures", "departures", fmt.Sprintf("%+v", journey.Departures.All))
+	ch := make(chan []TrainStop)
+	for _, train := range journey.Departures.All {
+		// We need to make a GET request on the timetable URL to retrieve array of stops.
+		// We also want to add whether that stop is on the designated journey or not.
+		go StopProducer(train.ServiceTimetable.Url, journey.StationCode, journey.DestinationCode, ch)
+		stops := StopConsumer(ch)
+		slog.Debug("Stopping point details", "train_uid", train.TrainUid, "stops", fmt.Sprintf("%+v", stops))
+		trips = append(trips, newTrainTrip(train, stops, journey))
+	}
+	return trips
+}
+
+// sortFastest orders trips by journey time, earliest departure first on a tie.
+// Trips we couldn't time go to the end.
+func sortFastest(trips []TrainTrip) {
+	sort.SliceStable(trips, func(i, j int) bool {
+		a, b := trips[i], trips[j]
+		if (len(a.DestinationArrival) > 0) != (len(b.DestinationArrival) > 0) {
+			return len(a.DestinationArrival) > 0
+		}
+		if a.DurationMins != b.DurationMins {
+			return a.DurationMins < b.DurationMins
+		}
+		return a.OriginDeparture < b.OriginDeparture
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestMinutesBetween(t *testing.T) {
+	tests := []struct {
+		from   string
+		to     string
+		want   int
+		wantOk bool
+	}{
+		{"10:00", "10:45", 45, true},
+		{"10:00", "10:00", 0, true},
+		{"23:50", "00:10", 20, true},
+		{"23:59", "00:00", 1, true},
+		{"00:10", "23:50", 1420, true},
+		{"", "10:00", 0, false},
+		{"10:00", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"-"+tt.to, func(t *testing.T) {
+			got, ok := minutesBetween(tt.from, tt.to)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("minutesBetween(%q, %q) = %d, %v, want %d, %v", tt.from, tt.to, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestNewTrainTrip(t *testing.T) {
+	stops := []TrainStop{
+		{StationCode: "OXF", AimedDeparture: "23:40", ExpectedDeparture: "23:44"},
+		{StationCode: "DID", AimedPass: "23:52"},
+		{StationCode: "RDG", AimedArrival: "00:02", AimedDeparture: "00:04"},
+		{StationCode: "PAD", AimedArrival: "00:35", ExpectedArrival: "00:38"},
+	}
+	train := TrainDeparture{TrainUid: "C1", AimedDeparture: "23:40"}
+	tests := []struct {
+		from         string
+		to           string
+		departure    string
+		arrival      string
+		duration     int
+		intermediate int
+	}{
+		{"OXF", "PAD", "23:44", "00:38", 54, 1},
+		{"OXF", "RDG", "23:44", "00:02", 18, 0},
+		{"RDG", "PAD", "00:04", "00:38", 34, 0},
+		// Not called at after the origin
+		{"PAD", "OXF", "23:40", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"-"+tt.to, func(t *testing.T) {
+			trip := newTrainTrip(train, stops, TrainJourney{StationCode: tt.from, DestinationCode: tt.to})
+			if trip.OriginDeparture != tt.departure || trip.DestinationArrival != tt.arrival ||
+				trip.DurationMins != tt.duration || trip.IntermediateStops != tt.intermediate {
+				t.Errorf("got %s -> %s %d mins %d stops, want %s -> %s %d mins %d stops",
+					trip.OriginDeparture, trip.DestinationArrival, trip.DurationMins, trip.IntermediateStops,
+					tt.departure, tt.arrival, tt.duration, tt.intermediate)
+			}
+		})
+	}
+}
+
+func TestLateness(t *testing.T) {
+	tests := []struct {
+		aimed  string
+		actual string
+		want   int
+		wantOk bool
+	}{
+		{"10:00", "10:07", 7, true},
+		{"10:00", "10:00", 0, true},
+		{"10:00", "09:58", -2, true},
+		{"23:55", "00:10", 15, true},
+		{"00:02", "23:59", -3, true},
+		// Twelve hours or more after is taken as early, before midnight
+		{"10:00", "22:00", -720, true},
+		{"10:00", "21:59", 719, true},
+		{"10:00", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aimed+"-"+tt.actual, func(t *testing.T) {
+			got, ok := lateness(tt.aimed, tt.actual)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("lateness(%q, %q) = %d, %v, want %d, %v", tt.aimed, tt.actual, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
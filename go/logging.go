@@ -0,0 +1,73 @@
+/*
+ logging.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Logging for trainsClient.go using log/slog, written to stderr so that it never
+mixes with the trains on stdout.  --verbose logs every transportAPI request and
+response at debug level and --log-json switches from text to one JSON object per
+line.  transportAPI credentials travel in the query string, including inside the
+service_timetable links it hands back, so app_id and app_key values are masked in
+everything logged including fatal errors.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"regexp"
+
+	grequests "github.com/levigross/grequests"
+)
+
+var credentialParam = regexp.MustCompile(`(?i)\b(app_id|app_key)=[^&\s"']*`)
+
+// redactURL masks credentials in a URL or in any text containing one
+func redactURL(text string) string {
+	return credentialParam.ReplaceAllString(text, "${1}=REDACTED")
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redactURL(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(redactURL(err.Error()))
+		}
+	}
+	return a
+}
+
+// setupLogging makes a redacting slog logger the default.  Output from the log
+// package, which is only used for fatal errors, goes through it at error level.
+func setupLogging(verbose bool, json bool) {
+	opts := &slog.HandlerOptions{Level: slog.LevelWarn, ReplaceAttr: redactAttr}
+	if verbose {
+		opts.Level = slog.LevelDebug
+	}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if json {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	slog.SetLogLoggerLevel(slog.LevelError)
+}
+
+// debugResponse logs a transportAPI request with the query string actually sent
+func debugResponse(resp *grequests.Response, body string) {
+	slog.Debug("transportAPI request", "url", resp.RawResponse.Request.URL.String(), "status", resp.StatusCode, "response", body)
+}
@@ -0,0 +1,466 @@
+/*
+ offline.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Offline timetable for when transportAPI is down or our quota has run out.
+Network Rail publish the full timetable every week as a CIF file, or in JSON as
+the SCHEDULE feed, and either can be downloaded once and kept locally, gzipped
+or not.  The loader reads the TIPLOC records to map timing points to CRS codes
+and keeps the schedules that run on the given date, picking the STP overlay,
+new or cancellation over the permanent schedule where there is one.  Calls with
+public times become stops and the rest of the timing points passes.  The result
+is indexed by station and public departure time so that --offline can answer a
+board query the way getTrainsCallingAt does, with scheduled departures and their
+calling points for the next two hours.  There are no live estimates, so the board
+is labelled as timetable only and every train has the status TIMETABLE ONLY.
+Operators are ATOC codes as the extracts don't carry names.
+
+Installation
+------------
+Download a full CIF extract from the Network Rail open data feeds, eg.
+$ curl -L -u "$NROD_USER:$NROD_PASS" -o timetable.cif.gz "https://publicdatafeeds.networkrail.co.uk/ntrod/CifFileAuthenticate?type=CIF_ALL_FULL_DAILY&day=toc-full.CIF.gz"
+$ go run . OXF PAD --offline --timetable=timetable.cif.gz
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const TIMETABLE_FILE = "timetable.cif"
+const OFFLINE_WINDOW_MINS = 120
+
+// Overnight trains from yesterday's schedules, such as the sleepers, can still be
+// calling until this many minutes after midnight
+const OFFLINE_OVERNIGHT_MINS = 8 * 60
+const TIMETABLE_ONLY = "TIMETABLE ONLY"
+
+// plannedSchedule is one train's schedule as read from a CIF or JSON extract
+type plannedSchedule struct {
+	TrainUid  string
+	Stp       string
+	Operator  string
+	Category  string
+	StartDate string
+	EndDate   string
+	DaysRun   string
+	Stops     []TrainStop
+}
+
+// runsOn reports whether the schedule is valid on date, given as YYYY-MM-DD
+func (s *plannedSchedule) runsOn(date string, weekday time.Weekday) bool {
+	if date < s.StartDate || (len(s.EndDate) > 0 && date > s.EndDate) {
+		return false
+	}
+	// Days run start on Monday
+	day := (int(weekday) + 6) % 7
+	return len(s.DaysRun) == 7 && s.DaysRun[day] == '1'
+}
+
+// stationDeparture is a public departure from a station in the index
+type stationDeparture struct {
+	Time     string
+	Schedule *plannedSchedule
+	Stop     int
+}
+
+type TimetableIndex struct {
+	Date       string
+	Departures map[string][]stationDeparture
+}
+
+// timetableLoader gathers the schedules for one date while an extract is read
+type timetableLoader struct {
+	date      string
+	weekday   time.Weekday
+	crs       map[string]string
+	names     map[string]string
+	schedules map[string]*plannedSchedule
+	current   *plannedSchedule
+}
+
+// add keeps s if it runs on the date and takes precedence over any schedule
+// already held for the train.  STP indicators C, N and O all beat P.
+func (l *timetableLoader) add(s *plannedSchedule) bool {
+	if !s.runsOn(l.date, l.weekday) {
+		return false
+	}
+	if held, ok := l.schedules[s.TrainUid]; ok && held.Stp <= s.Stp {
+		return false
+	}
+	l.schedules[s.TrainUid] = s
+	return true
+}
+
+// cifField returns columns start to end of a CIF record, counting from 1 as the
+// CIF specification does
+func cifField(line string, start int, end int) string {
+	if len(line) < start {
+		return ""
+	}
+	if len(line) < end {
+		end = len(line)
+	}
+	return strings.TrimSpace(line[start-1 : end])
+}
+
+// cifDate converts a yymmdd CIF date, where 999999 means no end date
+func cifDate(text string) string {
+	if len(text) != 6 || text == "999999" {
+		return ""
+	}
+	t, err := time.Parse("060102", text)
+	if err != nil {
+		return ""
+	}
+	return t.Format(DATE_FORMAT)
+}
+
+// scheduleTime converts "1010" or "1010H" to "10:10".  Public times of 0000 mean
+// there is no public call.
+func scheduleTime(text string, public bool) string {
+	text = strings.TrimSuffix(strings.TrimSpace(text), "H")
+	if len(text) != 4 || (public && text == "0000") {
+		return ""
+	}
+	return text[:2] + ":" + text[2:]
+}
+
+// plannedStop turns a timing point into a stop, or reports false for timing points
+// that are neither public calls nor passes
+func plannedStop(kind string, tiploc string, arrival string, departure string, pass string, platform string) (TrainStop, bool) {
+	stop := TrainStop{TiplocCode: tiploc, StopType: kind, Platform: platform}
+	switch {
+	case len(pass) > 0:
+		stop.AimedPass = pass
+		stop.Platform = ""
+	case len(arrival) > 0 || len(departure) > 0:
+		stop.AimedArrival = arrival
+		stop.AimedDeparture = departure
+	default:
+		return stop, false
+	}
+	return stop, true
+}
+
+func (l *timetableLoader) readCIF(r *bufio.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) < 2 {
+			continue
+		}
+		switch line[:2] {
+		case "TI", "TA":
+			tiploc := cifField(line, 3, 9)
+			if crs := cifField(line, 54, 56); len(crs) > 0 {
+				l.crs[tiploc] = crs
+			}
+			l.names[tiploc] = cifField(line, 19, 44)
+		case "BS":
+			l.current = nil
+			if cifField(line, 3, 3) == "D" {
+				continue
+			}
+			s := &plannedSchedule{
+				TrainUid:  cifField(line, 4, 9),
+				StartDate: cifDate(cifField(line, 10, 15)),
+				EndDate:   cifDate(cifField(line, 16, 21)),
+				DaysRun:   cifField(line, 22, 28),
+				Category:  cifField(line, 31, 32),
+				Stp:       cifField(line, 80, 80),
+			}
+			if l.add(s) {
+				l.current = s
+			}
+		case "BX":
+			if l.current != nil {
+				l.current.Operator = cifField(line, 12, 13)
+			}
+		case "LO":
+			if l.current != nil {
+				stop, ok := plannedStop("LO", cifField(line, 3, 9), "", scheduleTime(cifField(line, 16, 19), true), "", cifField(line, 20, 22))
+				if ok {
+					l.current.Stops = append(l.current.Stops, stop)
+				}
+			}
+		case "LI":
+			if l.current != nil {
+				stop, ok := plannedStop("LI", cifField(line, 3, 9), scheduleTime(cifField(line, 26, 29), true),
+					scheduleTime(cifField(line, 30, 33), true), scheduleTime(cifField(line, 21, 25), false), cifField(line, 34, 36))
+				if ok {
+					l.current.Stops = append(l.current.Stops, stop)
+				}
+			}
+		case "LT":
+			if l.current != nil {
+				stop, ok := plannedStop("LT", cifField(line, 3, 9), scheduleTime(cifField(line, 16, 19), true), "", "", cifField(line, 20, 22))
+				if ok {
+					l.current.Stops = append(l.current.Stops, stop)
+				}
+				l.current = nil
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// The parts of the Network Rail SCHEDULE feed we use, one JSON object per line
+type jsonTiploc struct {
+	TiplocCode     string `json:"tiploc_code"`
+	CrsCode        string `json:"crs_code"`
+	TpsDescription string `json:"tps_description"`
+}
+
+type jsonLocation struct {
+	LocationType    string `json:"location_type"`
+	TiplocCode      string `json:"tiploc_code"`
+	Pass            string `json:"pass"`
+	PublicArrival   string `json:"public_arrival"`
+	PublicDeparture string `json:"public_departure"`
+	Platform        string `json:"platform"`
+}
+
+type jsonSchedule struct {
+	TransactionType string `json:"transaction_type"`
+	TrainUid        string `json:"CIF_train_uid"`
+	StpIndicator    string `json:"CIF_stp_indicator"`
+	AtocCode        string `json:"atoc_code"`
+	DaysRuns        string `json:"schedule_days_runs"`
+	StartDate       string `json:"schedule_start_date"`
+	EndDate         string `json:"schedule_end_date"`
+	Segment         struct {
+		Category  string         `json:"CIF_train_category"`
+		Locations []jsonLocation `json:"schedule_location"`
+	} `json:"schedule_segment"`
+}
+
+type jsonRecord struct {
+	TiplocV1       *jsonTiploc   `json:"TiplocV1"`
+	JsonScheduleV1 *jsonSchedule `json:"JsonScheduleV1"`
+}
+
+func (l *timetableLoader) readJSON(r *bufio.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := jsonRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return err
+		}
+		if t := record.TiplocV1; t != nil {
+			if len(t.CrsCode) > 0 {
+				l.crs[t.TiplocCode] = t.CrsCode
+			}
+			l.names[t.TiplocCode] = t.TpsDescription
+		}
+		j := record.JsonScheduleV1
+		if j == nil || j.TransactionType == "Delete" {
+			continue
+		}
+		s := &plannedSchedule{
+			TrainUid:  j.TrainUid,
+			Stp:       j.StpIndicator,
+			Operator:  j.AtocCode,
+			Category:  j.Segment.Category,
+			StartDate: j.StartDate,
+			EndDate:   j.EndDate,
+			DaysRun:   j.DaysRuns,
+		}
+		if !l.add(s) {
+			continue
+		}
+		for _, loc := range j.Segment.Locations {
+			stop, ok := plannedStop(loc.LocationType, loc.TiplocCode, scheduleTime(loc.PublicArrival, true),
+				scheduleTime(loc.PublicDeparture, true), scheduleTime(loc.Pass, false), loc.Platform)
+			if ok {
+				s.Stops = append(s.Stops, stop)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// loadTimetable reads a CIF or JSON extract, gzipped or not, and indexes the
+// schedules running on date by station and public departure time
+func loadTimetable(timetable_file string, date string) *TimetableIndex {
+	day, err := time.Parse(DATE_FORMAT, date)
+	if err != nil {
+		log.Fatal("Invalid date, expected YYYY-MM-DD: ", date)
+	}
+	f, err := os.Open(timetable_file)
+	if err != nil {
+		log.Fatalf("Cannot read timetable %s: %v", timetable_file, err)
+	}
+	defer f.Close()
+	var in io.Reader = bufio.NewReader(f)
+	if magic, _ := in.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			log.Fatalf("Cannot read timetable %s: %v", timetable_file, err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+	r := bufio.NewReaderSize(in, 64*1024)
+	l := &timetableLoader{
+		date:      date,
+		weekday:   day.Weekday(),
+		crs:       make(map[string]string),
+		names:     make(map[string]string),
+		schedules: make(map[string]*plannedSchedule),
+	}
+	if first, _ := r.Peek(1); len(first) == 1 && first[0] == '{' {
+		err = l.readJSON(r)
+	} else {
+		err = l.readCIF(r)
+	}
+	if err != nil {
+		log.Fatalf("Cannot read timetable %s: %v", timetable_file, err)
+	}
+	return l.index(csvToJSONMap(STATION_NAMES_CSV))
+}
+
+// index fills in station codes and names and indexes the public departures
+func (l *timetableLoader) index(stations map[string]string) *TimetableIndex {
+	index := &TimetableIndex{Date: l.date, Departures: make(map[string][]stationDeparture)}
+	for _, s := range l.schedules {
+		// An STP cancellation means the train doesn't run on the date
+		if s.Stp == "C" {
+			continue
+		}
+		for i := range s.Stops {
+			stop := &s.Stops[i]
+			stop.StationCode = l.crs[stop.TiplocCode]
+			stop.StationName = stations[stop.StationCode]
+			if len(stop.StationName) == 0 {
+				stop.StationName = strings.Title(strings.ToLower(l.names[stop.TiplocCode]))
+			}
+			if len(stop.StationCode) > 0 && len(stop.AimedDeparture) > 0 {
+				index.Departures[stop.StationCode] = append(index.Departures[stop.StationCode], stationDeparture{Time: stop.AimedDeparture, Schedule: s, Stop: i})
+			}
+		}
+	}
+	for _, departures := range index.Departures {
+		sort.Slice(departures, func(i, j int) bool { return departures[i].Time < departures[j].Time })
+	}
+	return index
+}
+
+// loadOfflineTimetables loads the schedules for today, for yesterday early in the
+// morning while overnight trains are still running and, if the window from now runs
+// past midnight, for tomorrow as well
+func loadOfflineTimetables(timetable_file string, now time.Time) (*TimetableIndex, *TimetableIndex, *TimetableIndex) {
+	var yesterday, tomorrow *TimetableIndex
+	mins, _ := clockMinutes(now.Format("15:04"))
+	if mins < OFFLINE_OVERNIGHT_MINS {
+		yesterday = loadTimetable(timetable_file, now.AddDate(0, 0, -1).Format(DATE_FORMAT))
+	}
+	today := loadTimetable(timetable_file, now.Format(DATE_FORMAT))
+	if mins+OFFLINE_WINDOW_MINS >= MINUTES_PER_DAY {
+		tomorrow = loadTimetable(timetable_file, now.AddDate(0, 0, 1).Format(DATE_FORMAT))
+	}
+	return yesterday, today, tomorrow
+}
+
+// departureMinutes is when a departure in an index for a date happens, in minutes from
+// the start of that date.  CIF times don't carry a date so a call earlier in the day
+// than the train's first departure is after midnight.
+func departureMinutes(departure stationDeparture) (int, bool) {
+	mins, ok := clockMinutes(departure.Time)
+	if !ok {
+		return 0, false
+	}
+	if first, ok := clockMinutes(scheduledTime(departure.Schedule.Stops[0])); ok && mins < first {
+		mins += MINUTES_PER_DAY
+	}
+	return mins, true
+}
+
+// getOfflineTrainsCallingAt is getTrainsCallingAt from the timetable indexes for today,
+// yesterday for trains still running after midnight and, when the two hours from clock
+// run past midnight, tomorrow.  Either of those may be nil.  It returns the trips as
+// getTrainTrips would.
+func getOfflineTrainsCallingAt(yesterday *TimetableIndex, today *TimetableIndex, tomorrow *TimetableIndex, station_code string, station_name string, dest_code string, dest_name string, clock string) (TrainJourney, []TrainTrip) {
+	journey := TrainJourney{
+		Date:            today.Date,
+		TimeOfDay:       clock,
+		StationName:     station_name,
+		StationCode:     station_code,
+		DestinationName: dest_name,
+		DestinationCode: dest_code,
+		TimetableOnly:   true,
+	}
+	from, ok := clockMinutes(clock)
+	if !ok {
+		log.Fatal("Invalid time, expected HH:MM: ", clock)
+	}
+	type candidate struct {
+		departure stationDeparture
+		mins      int
+	}
+	var candidates []candidate
+	for day, index := range []*TimetableIndex{yesterday, today, tomorrow} {
+		if index == nil {
+			continue
+		}
+		for _, departure := range index.Departures[station_code] {
+			mins, ok := departureMinutes(departure)
+			mins += (day - 1) * MINUTES_PER_DAY
+			if ok && mins >= from && mins <= from+OFFLINE_WINDOW_MINS {
+				candidates = append(candidates, candidate{departure: departure, mins: mins})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].mins < candidates[j].mins })
+	var trips []TrainTrip
+	for _, c := range candidates {
+		s := c.departure.Schedule
+		calls := false
+		for _, stop := range s.Stops[c.departure.Stop+1:] {
+			if stop.StationCode == dest_code && len(stop.AimedArrival) > 0 {
+				calls = true
+				break
+			}
+		}
+		if !calls {
+			continue
+		}
+		stop := s.Stops[c.departure.Stop]
+		train := TrainDeparture{
+			Mode:            "train",
+			TrainUid:        s.TrainUid,
+			Platform:        stop.Platform,
+			Operator:        s.Operator,
+			AimedDeparture:  stop.AimedDeparture,
+			AimedArrival:    stop.AimedArrival,
+			OriginName:      s.Stops[0].StationName,
+			DestinationName: s.Stops[len(s.Stops)-1].StationName,
+			Source:          "Network Rail timetable",
+			Category:        s.Category,
+			Status:          TIMETABLE_ONLY,
+		}
+		journey.Departures.All = append(journey.Departures.All, train)
+		trips = append(trips, newTrainTrip(train, markOnRoute(s.Stops, station_code, dest_code), journey))
+	}
+	return journey, trips
+}
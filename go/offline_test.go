@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCifField(t *testing.T) {
+	line := "BSNC208031910261910260000001 POO2N53    122450003 EMU333 100D     B            P"
+	tests := []struct {
+		start int
+		end   int
+		want  string
+	}{
+		{1, 2, "BS"},
+		{4, 9, "C20803"},
+		{10, 15, "191026"},
+		{80, 80, "P"},
+		// Past the end of a short record
+		{81, 85, ""},
+		{79, 85, "P"},
+	}
+	for _, tt := range tests {
+		if got := cifField(line, tt.start, tt.end); got != tt.want {
+			t.Errorf("cifField(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestPlannedStop(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrival   string
+		departure string
+		pass      string
+		platform  string
+		want      TrainStop
+		wantOk    bool
+	}{
+		{"call", "10:14", "10:16", "", "4", TrainStop{TiplocCode: "RDNGSTN", StopType: "LI", AimedArrival: "10:14", AimedDeparture: "10:16", Platform: "4"}, true},
+		{"pass has no platform", "", "", "10:20", "4", TrainStop{TiplocCode: "RDNGSTN", StopType: "LI", AimedPass: "10:20"}, true},
+		{"no public times", "", "", "", "4", TrainStop{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := plannedStop("LI", "RDNGSTN", tt.arrival, tt.departure, tt.pass, tt.platform)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimetableLoaderSTPPrecedence(t *testing.T) {
+	// 2019-10-26 is a Saturday
+	schedule := func(stp string) *plannedSchedule {
+		return &plannedSchedule{TrainUid: "C20803", Stp: stp, StartDate: "2019-10-01", EndDate: "2019-10-31", DaysRun: "1111111"}
+	}
+	tests := []struct {
+		held string
+		add  string
+		want string
+	}{
+		{"P", "O", "O"},
+		{"O", "P", "O"},
+		{"P", "N", "N"},
+		{"O", "C", "C"},
+		{"C", "O", "C"},
+		{"P", "P", "P"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.held+" then "+tt.add, func(t *testing.T) {
+			l := &timetableLoader{date: "2019-10-26", weekday: time.Saturday, schedules: make(map[string]*plannedSchedule)}
+			l.add(schedule(tt.held))
+			l.add(schedule(tt.add))
+			if got := l.schedules["C20803"].Stp; got != tt.want {
+				t.Errorf("held STP %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	l := &timetableLoader{date: "2019-10-26", weekday: time.Saturday, schedules: make(map[string]*plannedSchedule)}
+	weekdays := schedule("P")
+	weekdays.DaysRun = "1111100"
+	if l.add(weekdays) {
+		t.Errorf("weekday schedule added on a Saturday")
+	}
+	expired := schedule("P")
+	expired.EndDate = "2019-10-25"
+	if l.add(expired) {
+		t.Errorf("schedule added after its end date")
+	}
+}
+
+func testIndex(date string, schedules ...*plannedSchedule) *TimetableIndex {
+	index := &TimetableIndex{Date: date, Departures: make(map[string][]stationDeparture)}
+	for _, s := range schedules {
+		for i, stop := range s.Stops {
+			if len(stop.AimedDeparture) > 0 {
+				index.Departures[stop.StationCode] = append(index.Departures[stop.StationCode], stationDeparture{Time: stop.AimedDeparture, Schedule: s, Stop: i})
+			}
+		}
+	}
+	return index
+}
+
+func testSchedule(uid string, departs string, arrives string) *plannedSchedule {
+	return &plannedSchedule{TrainUid: uid, Stops: []TrainStop{
+		{StationCode: "OXF", StationName: "Oxford", AimedDeparture: departs},
+		{StationCode: "PAD", StationName: "London Paddington", AimedArrival: arrives},
+	}}
+}
+
+func TestGetOfflineTrainsCallingAtPastMidnight(t *testing.T) {
+	today := testIndex("2019-10-26",
+		testSchedule("EARLY", "06:00", "07:00"),
+		testSchedule("LATE1", "23:10", "00:10"),
+		testSchedule("LATE2", "23:55", "00:55"))
+	tomorrow := testIndex("2019-10-27",
+		testSchedule("NIGHT", "00:30", "01:30"),
+		testSchedule("TOOLATE", "01:30", "02:30"))
+	// Yesterday's trains from Birmingham reach Oxford around midnight
+	overnight := func(uid string, starts string, departs string, arrives string) *plannedSchedule {
+		return &plannedSchedule{TrainUid: uid, Stops: []TrainStop{
+			{StationCode: "BHM", StationName: "Birmingham New Street", AimedDeparture: starts},
+			{StationCode: "OXF", StationName: "Oxford", AimedArrival: departs, AimedDeparture: departs},
+			{StationCode: "PAD", StationName: "London Paddington", AimedArrival: arrives},
+		}}
+	}
+	yesterday := testIndex("2019-10-25",
+		overnight("YDAY1", "23:10", "00:40", "01:40"),
+		overnight("YDAY2", "22:00", "23:20", "00:20"),
+		testSchedule("YDAY3", "00:45", "01:45"))
+	tests := []struct {
+		name      string
+		yesterday *TimetableIndex
+		tomorrow  *TimetableIndex
+		clock     string
+		want      []string
+	}{
+		{"wraps midnight", nil, tomorrow, "23:00", []string{"LATE1", "LATE2", "NIGHT"}},
+		// YDAY2 left Oxford before midnight and YDAY3 early yesterday morning
+		{"after midnight", yesterday, nil, "00:15", []string{"YDAY1"}},
+		{"morning", yesterday, nil, "05:30", []string{"EARLY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, trips := getOfflineTrainsCallingAt(tt.yesterday, today, tt.tomorrow, "OXF", "Oxford", "PAD", "London Paddington", tt.clock)
+			var got []string
+			for _, trip := range trips {
+				got = append(got, trip.TrainUid)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+/*
+ planner.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Utility module to plan journeys that need one change of train, eg. TWY to OXF via RDG.
+Two live boards are combined: trains from the origin to the interchange and trains
+from the interchange to the destination.  A connection is only feasible if the second
+train leaves at least the minimum connection time after the first one arrives.
+When no interchange is given we try the stations most often called at by trains
+leaving the origin.  That needs a timetable call for every train considered so only
+the first few trains from the origin are used, and from each interchange only the
+first few that one of them could connect with.  A through train is never paired with
+itself.  Direct trains are included so they can be compared like for like.
+Feasible journeys are ranked by final arrival time.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const MAX_CHANGE_MINS = 90
+const MAX_INTERCHANGES = 3
+const MAX_PLAN_DEPARTURES = 6
+
+type TrainConnection struct {
+	DepartureTime string      `json:"departure_time"`
+	ArrivalTime   string      `json:"arrival_time"`
+	DurationMins  int         `json:"duration_mins"`
+	ViaCode       string      `json:"via_code,omitempty"`
+	ViaName       string      `json:"via_name,omitempty"`
+	ChangeMins    int         `json:"change_mins"`
+	Legs          []TrainTrip `json:"legs"`
+}
+
+type TrainPlan struct {
+	Date            string            `json:"date"`
+	TimeOfDay       string            `json:"time_of_day"`
+	StationName     string            `json:"station_name"`
+	StationCode     string            `json:"station_code"`
+	DestinationName string            `json:"destination_name"`
+	DestinationCode string            `json:"destination_code"`
+	MinChangeMins   int               `json:"min_change_mins"`
+	Journeys        []TrainConnection `json:"journeys"`
+}
+
+func newDirectConnection(trip TrainTrip) TrainConnection {
+	return TrainConnection{
+		DepartureTime: trip.OriginDeparture,
+		ArrivalTime:   trip.DestinationArrival,
+		DurationMins:  trip.DurationMins,
+		Legs:          []TrainTrip{trip},
+	}
+}
+
+// connectLegs pairs every first leg with the second leg that gets to the destination
+// soonest while leaving enough time to change trains.
+func connectLegs(first []TrainTrip, second []TrainTrip, viaCode string, viaName string, minChange int) []TrainConnection {
+	var connections []TrainConnection
+	for _, a := range first {
+		if len(a.DestinationArrival) == 0 {
+			continue
+		}
+		var best *TrainConnection
+		for _, b := range second {
+			// Staying on a through train isn't a change
+			if len(b.DestinationArrival) == 0 || b.TrainUid == a.TrainUid {
+				continue
+			}
+			wait, ok := minutesBetween(a.DestinationArrival, b.OriginDeparture)
+			if !ok || wait < minChange || wait > MAX_CHANGE_MINS {
+				continue
+			}
+			duration, _ := minutesBetween(a.OriginDeparture, b.DestinationArrival)
+			if best != nil && duration >= best.DurationMins {
+				continue
+			}
+			best = &TrainConnection{
+				DepartureTime: a.OriginDeparture,
+				ArrivalTime:   b.DestinationArrival,
+				DurationMins:  duration,
+				ViaCode:       viaCode,
+				ViaName:       viaName,
+				ChangeMins:    wait,
+				Legs:          []TrainTrip{a, b},
+			}
+		}
+		if best != nil {
+			connections = append(connections, *best)
+		}
+	}
+	return connections
+}
+
+// firstDepartures keeps the first n trains on a board
+func firstDepartures(journey TrainJourney, n int) TrainJourney {
+	if len(journey.Departures.All) > n {
+		journey.Departures.All = journey.Departures.All[:n]
+	}
+	return journey
+}
+
+// connectingDepartures keeps the first few trains on the onward board that one of the
+// first legs could connect with, before we fetch their timetables
+func connectingDepartures(onward TrainJourney, first []TrainTrip, minChange int) TrainJourney {
+	var departures []TrainDeparture
+	for _, train := range onward.Departures.All {
+		departs := train.ExpectedDeparture
+		if len(departs) == 0 {
+			departs = train.AimedDeparture
+		}
+		for _, a := range first {
+			if len(a.DestinationArrival) == 0 || a.TrainUid == train.TrainUid {
+				continue
+			}
+			if wait, ok := minutesBetween(a.DestinationArrival, departs); ok && wait >= minChange && wait <= MAX_CHANGE_MINS {
+				departures = append(departures, train)
+				break
+			}
+		}
+		if len(departures) == MAX_PLAN_DEPARTURES {
+			break
+		}
+	}
+	onward.Departures.All = departures
+	return onward
+}
+
+// retimeTrips works out timings for the trips from the origin as far as another station
+func retimeTrips(trips []TrainTrip, stationCode string, destCode string) []TrainTrip {
+	var retimed []TrainTrip
+	journey := TrainJourney{StationCode: stationCode, DestinationCode: destCode}
+	for _, trip := range trips {
+		retimed = append(retimed, newTrainTrip(trip.TrainDeparture, trip.Stops, journey))
+	}
+	return retimed
+}
+
+// candidateInterchanges returns the stations most often called at after the origin
+func candidateInterchanges(trips []TrainTrip, stationCode string, destCode string) []TrainStop {
+	counts := make(map[string]int)
+	var stations []TrainStop
+	for _, trip := range trips {
+		seen := make(map[string]bool)
+		past := false
+		for _, stop := range trip.Stops {
+			if stop.StationCode == stationCode {
+				past = true
+				continue
+			}
+			if !past || stop.IsPass() || stop.StationCode == destCode || seen[stop.StationCode] {
+				continue
+			}
+			seen[stop.StationCode] = true
+			if counts[stop.StationCode] == 0 {
+				stations = append(stations, stop)
+			}
+			counts[stop.StationCode]++
+		}
+	}
+	sort.SliceStable(stations, func(i, j int) bool {
+		return counts[stations[i].StationCode] > counts[stations[j].StationCode]
+	})
+	if len(stations) > MAX_INTERCHANGES {
+		stations = stations[:MAX_INTERCHANGES]
+	}
+	return stations
+}
+
+// rankConnections orders journeys by final arrival, measured from the board time so
+// that journeys arriving after midnight sort after those arriving before it.
+func rankConnections(connections []TrainConnection, timeOfDay string) {
+	ref, ok := clockMinutes(timeOfDay)
+	if !ok {
+		ref = 0
+	}
+	ref = (ref - 60 + MINUTES_PER_DAY) % MINUTES_PER_DAY
+	elapsed := func(clock string) int {
+		mins, _ := clockMinutes(clock)
+		return (mins - ref + MINUTES_PER_DAY) % MINUTES_PER_DAY
+	}
+	sort.SliceStable(connections, func(i, j int) bool {
+		a, b := connections[i], connections[j]
+		if elapsed(a.ArrivalTime) != elapsed(b.ArrivalTime) {
+			return elapsed(a.ArrivalTime) < elapsed(b.ArrivalTime)
+		}
+		return elapsed(a.DepartureTime) > elapsed(b.DepartureTime)
+	})
+}
+
+// dedupeConnections keeps the best ranked journey for each final train so that we
+// don't list every earlier train that happens to make the same connection.
+func dedupeConnections(connections []TrainConnection) []TrainConnection {
+	var deduped []TrainConnection
+	seen := make(map[string]bool)
+	for _, connection := range connections {
+		last := connection.Legs[len(connection.Legs)-1]
+		if seen[last.TrainUid] {
+			continue
+		}
+		seen[last.TrainUid] = true
+		deduped = append(deduped, connection)
+	}
+	return deduped
+}
+
+func planJourney(stationCode string, stationName string, destCode string, destName string, viaCode string, minChange int) TrainPlan {
+	var connections []TrainConnection
+	var origin TrainJourney
+	if len(viaCode) > 0 {
+		_, viaName := validateInputs(stationCode, viaCode)
+		origin = getTrainsCallingAt(stationCode, stationName, viaCode, viaName)
+		first := getTrainTrips(origin)
+		onward := getTrainsCallingAt(viaCode, viaName, destCode, destName)
+		second := getTrainTrips(connectingDepartures(onward, first, minChange))
+		connections = connectLegs(first, second, viaCode, viaName, minChange)
+	} else {
+		// An empty destination gives us every departure from the origin
+		origin = getTrainsCallingAt(stationCode, stationName, "", "")
+		trips := getTrainTrips(firstDepartures(origin, MAX_PLAN_DEPARTURES))
+		for _, trip := range retimeTrips(trips, stationCode, destCode) {
+			if len(trip.DestinationArrival) > 0 {
+				connections = append(connections, newDirectConnection(trip))
+			}
+		}
+		for _, via := range candidateInterchanges(trips, stationCode, destCode) {
+			slog.Debug("Trying interchange", "station_name", via.StationName, "station_code", via.StationCode)
+			first := retimeTrips(trips, stationCode, via.StationCode)
+			onward := getTrainsCallingAt(via.StationCode, via.StationName, destCode, destName)
+			second := getTrainTrips(connectingDepartures(onward, first, minChange))
+			connections = append(connections, connectLegs(first, second, via.StationCode, via.StationName, minChange)...)
+		}
+	}
+	rankConnections(connections, origin.TimeOfDay)
+	connections = dedupeConnections(connections)
+	return TrainPlan{
+		Date:            origin.Date,
+		TimeOfDay:       origin.TimeOfDay,
+		StationName:     stationName,
+		StationCode:     stationCode,
+		DestinationName: destName,
+		DestinationCode: destCode,
+		MinChangeMins:   minChange,
+		Journeys:        connections,
+	}
+}
+
+func formatPlanHeader(plan TrainPlan) string {
+	header := fmt.Sprintf("==== Journeys from %s (%s) to %s", plan.StationName, plan.StationCode, plan.DestinationName)
+	header += fmt.Sprintf("(%s) %s %s ====", plan.DestinationCode, plan.TimeOfDay, plan.Date)
+	return header
+}
+
+func formatLeg(trip TrainTrip) string {
+	leg := fmt.Sprintf("\t%s %s -> %s %s", trip.FromCode, trip.OriginDeparture, trip.ToCode, trip.DestinationArrival)
+	leg += fmt.Sprintf("  Train %s (%s) => %s", trip.TrainUid, trip.Operator, trip.Status)
+	return leg
+}
+
+func formatConnection(connection TrainConnection, plan TrainPlan) string {
+	changes := "direct"
+	if len(connection.ViaCode) > 0 {
+		changes = fmt.Sprintf("change at %s", connection.ViaName)
+	}
+	journey := fmt.Sprintf("%s %s -> %s %s", plan.StationCode, connection.DepartureTime, plan.DestinationCode, connection.ArrivalTime)
+	journey += fmt.Sprintf(" (%d mins, %s)", connection.DurationMins, changes)
+	for i, leg := range connection.Legs {
+		if i > 0 {
+			journey += fmt.Sprintf("\n\t%d mins to change at %s", connection.ChangeMins, connection.ViaName)
+		}
+		journey += "\n" + formatLeg(leg)
+	}
+	return journey
+}
+
+func printPlanTable(plan TrainPlan) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPART\tARRIVE\tMINS\tVIA\tCHANGE\tTRAINS")
+	for _, connection := range plan.Journeys {
+		var trains []string
+		for _, leg := range connection.Legs {
+			trains = append(trains, leg.TrainUid)
+		}
+		via, change := "-", "-"
+		if len(connection.ViaCode) > 0 {
+			via, change = connection.ViaCode, fmt.Sprintf("%d", connection.ChangeMins)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", connection.DepartureTime, connection.ArrivalTime,
+			connection.DurationMins, via, change, strings.Join(trains, ","))
+	}
+	w.Flush()
+}
+
+func formatPlan(plan TrainPlan, format string) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			log.Fatal("Cannot serialize JSON: ", err)
+		}
+		fmt.Print(string(data))
+	case "table":
+		printHeader(formatPlanHeader(plan))
+		printPlanTable(plan)
+	default:
+		printHeader(formatPlanHeader(plan))
+		for _, connection := range plan.Journeys {
+			fmt.Println(formatConnection(connection, plan))
+		}
+	}
+	fmt.Println()
+}
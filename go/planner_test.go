@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func leg(uid string, departs string, arrives string) TrainTrip {
+	trip := TrainTrip{OriginDeparture: departs, DestinationArrival: arrives}
+	trip.TrainUid = uid
+	return trip
+}
+
+func describeConnections(connections []TrainConnection) string {
+	var described []string
+	for _, c := range connections {
+		var uids []string
+		for _, l := range c.Legs {
+			uids = append(uids, l.TrainUid)
+		}
+		described = append(described, fmt.Sprintf("%s %d/%d", strings.Join(uids, "+"), c.ChangeMins, c.DurationMins))
+	}
+	return strings.Join(described, ", ")
+}
+
+func TestConnectLegs(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  []TrainTrip
+		second []TrainTrip
+		want   string
+	}{
+		{"quickest onward train",
+			[]TrainTrip{leg("A1", "10:00", "10:25")},
+			[]TrainTrip{leg("B1", "10:28", "10:55"), leg("B2", "10:35", "11:05"), leg("B3", "10:40", "11:00")},
+			"A1+B3 15/60"},
+		{"too long to wait",
+			[]TrainTrip{leg("A1", "10:00", "10:25")},
+			[]TrainTrip{leg("B1", "12:00", "12:30")},
+			""},
+		{"not through the interchange",
+			[]TrainTrip{leg("A1", "10:00", "")},
+			[]TrainTrip{leg("B1", "10:40", "11:00")},
+			""},
+		{"a through train is not a connection with itself",
+			[]TrainTrip{leg("A1", "10:00", "10:25")},
+			[]TrainTrip{leg("A1", "10:31", "10:55"), leg("B1", "10:40", "11:10")},
+			"A1+B1 15/70"},
+		{"change after midnight",
+			[]TrainTrip{leg("C1", "23:40", "23:55")},
+			[]TrainTrip{leg("D1", "23:58", "00:40"), leg("D2", "00:05", "00:30")},
+			"C1+D2 10/50"},
+		{"each first leg",
+			[]TrainTrip{leg("A1", "10:00", "10:25"), leg("A2", "10:30", "10:55")},
+			[]TrainTrip{leg("B3", "10:40", "11:00"), leg("B4", "11:05", "11:35")},
+			"A1+B3 15/60, A2+B4 10/65"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeConnections(connectLegs(tt.first, tt.second, "RDG", "Reading", 5))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankConnections(t *testing.T) {
+	connection := func(departs string, arrives string) TrainConnection {
+		return TrainConnection{DepartureTime: departs, ArrivalTime: arrives}
+	}
+	tests := []struct {
+		timeOfDay   string
+		connections []TrainConnection
+		want        string
+	}{
+		{"10:00",
+			[]TrainConnection{connection("10:05", "11:30"), connection("10:10", "11:00"), connection("10:20", "11:00")},
+			"10:20-11:00 10:10-11:00 10:05-11:30"},
+		// Arrivals after midnight come after those before it
+		{"23:30",
+			[]TrainConnection{connection("23:35", "00:20"), connection("23:32", "23:50"), connection("23:35", "00:10"), connection("23:45", "00:10")},
+			"23:32-23:50 23:45-00:10 23:35-00:10 23:35-00:20"},
+		// A train that left just before the board time still sorts first
+		{"00:10",
+			[]TrainConnection{connection("00:20", "01:00"), connection("23:55", "00:40")},
+			"23:55-00:40 00:20-01:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timeOfDay, func(t *testing.T) {
+			rankConnections(tt.connections, tt.timeOfDay)
+			var got []string
+			for _, c := range tt.connections {
+				got = append(got, c.DepartureTime+"-"+c.ArrivalTime)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectingDepartures(t *testing.T) {
+	first := []TrainTrip{leg("A1", "10:00", "10:25")}
+	onward := TrainJourney{StationCode: "RDG"}
+	for i, departs := range []string{"10:20", "10:28", "10:27", "10:35", "10:40", "10:45", "10:50", "10:55", "11:00", "11:05", "12:00"} {
+		onward.Departures.All = append(onward.Departures.All, TrainDeparture{TrainUid: fmt.Sprintf("B%d", i), AimedDeparture: departs})
+	}
+	// The through train itself
+	onward.Departures.All[2].TrainUid = "A1"
+	// Expected times win over aimed
+	onward.Departures.All[0].ExpectedDeparture = "10:31"
+
+	var got []string
+	for _, train := range connectingDepartures(onward, first, 5).Departures.All {
+		got = append(got, train.TrainUid)
+	}
+	if want := "B0 B3 B4 B5 B6 B7"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ platforms.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Platform prediction from the platforms trains have used before.
+Platforms are often only announced a few minutes before a train arrives but the
+same train tends to use the same platform day after day.  Every board, service
+and recorder snapshot notes the platform given for each stop in a local bbolt
+database, keyed by station, scheduled time and train_uid eg. "RDG/10:16/C20803".
+Each train is counted once a day using the last platform seen that day so that
+repeated queries or a late platform change don't skew the counts.  When the live
+platform is empty and we have seen the train at least 3 times we show the
+platform it used most often as eg. "likely platform 4 (87%)".  The commands that
+show platforms call learnPlatforms themselves once they have their trips, and the
+database lives in trains_platforms.db unless --platforms says otherwise.  It is
+only a hint so any problem with it is logged rather than fatal.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const PLATFORMS_DB = "trains_platforms.db"
+const MIN_PLATFORM_SIGHTINGS = 3
+
+var platformsBucket = []byte("platforms")
+
+// PlatformHistory counts the days a train was seen at a station on each platform
+type PlatformHistory struct {
+	Counts   map[string]int `json:"counts"`
+	Date     string         `json:"date"`
+	Platform string         `json:"platform"`
+}
+
+type PlatformPrediction struct {
+	Platform  string `json:"platform"`
+	Pct       int    `json:"pct"`
+	Sightings int    `json:"sightings"`
+}
+
+func platformKey(station_code string, scheduled string, train_uid string) []byte {
+	return []byte(station_code + "/" + scheduled + "/" + train_uid)
+}
+
+// scheduledTime is the aimed time a train calls at a stop
+func scheduledTime(stop TrainStop) string {
+	if len(stop.AimedDeparture) > 0 {
+		return stop.AimedDeparture
+	}
+	return stop.AimedArrival
+}
+
+// see counts a platform for the given date, replacing any seen earlier that day
+func (h *PlatformHistory) see(platform string, date string) {
+	if h.Counts == nil {
+		h.Counts = make(map[string]int)
+	}
+	if h.Date == date {
+		if h.Platform == platform {
+			return
+		}
+		if h.Counts[h.Platform]--; h.Counts[h.Platform] <= 0 {
+			delete(h.Counts, h.Platform)
+		}
+	}
+	h.Counts[platform]++
+	h.Date = date
+	h.Platform = platform
+}
+
+// predict returns the platform used most often, or nil if we haven't seen the train enough
+func (h PlatformHistory) predict() *PlatformPrediction {
+	total := 0
+	best := ""
+	for platform, n := range h.Counts {
+		total += n
+		if n > h.Counts[best] || (n == h.Counts[best] && platform < best) {
+			best = platform
+		}
+	}
+	if total < MIN_PLATFORM_SIGHTINGS {
+		return nil
+	}
+	pct := int(math.Round(100 * float64(h.Counts[best]) / float64(total)))
+	return &PlatformPrediction{Platform: best, Pct: pct, Sightings: total}
+}
+
+// learnPlatforms records the platforms given for the stops of each trip on date in
+// db_file and fills in a likely platform for stops that don't have one yet
+func learnPlatforms(db_file string, trips []TrainTrip, date string) {
+	if len(date) == 0 {
+		date = time.Now().Format(DATE_FORMAT)
+	}
+	db, err := bolt.Open(db_file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		slog.Warn("Cannot open platform history", "file", db_file, "error", err)
+		return
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(platformsBucket)
+		if err != nil {
+			return err
+		}
+		for i := range trips {
+			for j := range trips[i].Stops {
+				stop := &trips[i].Stops[j]
+				scheduled := scheduledTime(*stop)
+				if len(scheduled) == 0 {
+					continue
+				}
+				key := platformKey(stop.StationCode, scheduled, trips[i].TrainUid)
+				history := PlatformHistory{}
+				if data := bucket.Get(key); data != nil {
+					if err := json.Unmarshal(data, &history); err != nil {
+						return fmt.Errorf("%s: %v", key, err)
+					}
+				}
+				if len(stop.Platform) == 0 {
+					stop.LikelyPlatform = history.predict()
+					continue
+				}
+				history.see(stop.Platform, date)
+				data, err := json.Marshal(history)
+				if err != nil {
+					return err
+				}
+				if err := bucket.Put(key, data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("Cannot update platform history", "file", db_file, "error", err)
+	}
+}
+
+// formatPlatform gives the live platform for a stop or, failing that, the likely one
+func formatPlatform(stop TrainStop) string {
+	if len(stop.Platform) == 0 && stop.LikelyPlatform != nil {
+		return fmt.Sprintf("likely platform %s (%d%%)", stop.LikelyPlatform.Platform, stop.LikelyPlatform.Pct)
+	}
+	return fmt.Sprintf("platform %s", stop.Platform)
+}
+
+// tablePlatform is formatPlatform for a table column, eg. "4" or "likely 4 (87%)"
+func tablePlatform(stop TrainStop) string {
+	if len(stop.Platform) == 0 && stop.LikelyPlatform != nil {
+		return fmt.Sprintf("likely %s (%d%%)", stop.LikelyPlatform.Platform, stop.LikelyPlatform.Pct)
+	}
+	return stop.Platform
+}
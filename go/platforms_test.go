@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlatformHistorySee(t *testing.T) {
+	tests := []struct {
+		name  string
+		seen  [][2]string
+		want  map[string]int
+		final string
+	}{
+		{"one a day", [][2]string{{"4", "2019-10-24"}, {"4", "2019-10-25"}, {"5", "2019-10-26"}}, map[string]int{"4": 2, "5": 1}, "5"},
+		{"same day twice", [][2]string{{"4", "2019-10-26"}, {"4", "2019-10-26"}}, map[string]int{"4": 1}, "4"},
+		{"late change", [][2]string{{"4", "2019-10-25"}, {"4", "2019-10-26"}, {"9", "2019-10-26"}}, map[string]int{"4": 1, "9": 1}, "9"},
+		{"changed back", [][2]string{{"4", "2019-10-26"}, {"9", "2019-10-26"}, {"4", "2019-10-26"}}, map[string]int{"4": 1}, "4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h PlatformHistory
+			for _, seen := range tt.seen {
+				h.see(seen[0], seen[1])
+			}
+			if !reflect.DeepEqual(h.Counts, tt.want) || h.Platform != tt.final {
+				t.Errorf("got %v last %s, want %v last %s", h.Counts, h.Platform, tt.want, tt.final)
+			}
+		})
+	}
+}
+
+func TestPlatformHistoryPredict(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   *PlatformPrediction
+	}{
+		{"never seen", nil, nil},
+		{"not seen enough", map[string]int{"4": 2}, nil},
+		{"enough", map[string]int{"4": 3}, &PlatformPrediction{Platform: "4", Pct: 100, Sightings: 3}},
+		{"most often", map[string]int{"4": 1, "9": 6}, &PlatformPrediction{Platform: "9", Pct: 86, Sightings: 7}},
+		{"tie goes to the lowest", map[string]int{"9": 2, "10": 2}, &PlatformPrediction{Platform: "10", Pct: 50, Sightings: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlatformHistory{Counts: tt.counts}.predict()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("predict() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLearnPlatforms(t *testing.T) {
+	db_file := filepath.Join(t.TempDir(), "platforms.db")
+	trip := func(platform string) []TrainTrip {
+		stop := TrainStop{StationCode: "RDG", AimedDeparture: "10:16", Platform: platform}
+		return []TrainTrip{{TrainDeparture: TrainDeparture{TrainUid: "C20803"}, Stops: []TrainStop{stop}}}
+	}
+	for _, date := range []string{"2019-10-23", "2019-10-24", "2019-10-24", "2019-10-25"} {
+		learnPlatforms(db_file, trip("9"), date)
+	}
+
+	trips := trip("")
+	learnPlatforms(db_file, trips, "2019-10-26")
+	want := &PlatformPrediction{Platform: "9", Pct: 100, Sightings: 3}
+	if got := trips[0].Stops[0].LikelyPlatform; !reflect.DeepEqual(got, want) {
+		t.Errorf("likely platform = %+v, want %+v", got, want)
+	}
+	if got := tablePlatform(trips[0].Stops[0]); got != "likely 9 (100%)" {
+		t.Errorf("tablePlatform = %q", got)
+	}
+	trips = trip("4")
+	learnPlatforms(db_file, trips, "2019-10-26")
+	if trips[0].Stops[0].LikelyPlatform != nil || tablePlatform(trips[0].Stops[0]) != "4" {
+		t.Errorf("live platform 4 shown as %q", tablePlatform(trips[0].Stops[0]))
+	}
+}
@@ -0,0 +1,420 @@
+/*
+ recorder.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Recorder that builds up a history of how punctual trains really are.
+Every few minutes it fetches the live board for each configured route, exactly as
+trainsClient.go does for a single query, and stores one record per train per day
+in a local bbolt database.  Each record keeps the aimed, expected and final times
+at both ends of the route along with the status and platforms.  Expected times
+are the latest estimate seen.  Final times are the estimates seen once the time
+had passed, which is as close as transportAPI gets to actual times.  Records live
+in one bucket per route keyed by date and train_uid, eg. "2019-10-26/C20803" in
+bucket "OXF:PAD", so a range of days can be read back in order.  The date is the
+one the service runs on, as given by its timetable, rather than the date of the
+board, so a train just after midnight on a board fetched before it is kept under
+the next day.  Times on a service after midnight fall on the day after that.
+Routes are given as FROM:TO arguments or in a file with one route per line.
+Failed requests are logged and retried on the next snapshot rather than ending
+the recorder.
+
+Installation
+------------
+$ go get go.etcd.io/bbolt
+$ go run . record OXF:PAD PAD:OXF --interval=5
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const HISTORY_DB = "trains_history.db"
+
+type Route struct {
+	From     string
+	FromName string
+	To       string
+	ToName   string
+}
+
+// ServiceRecord is what we know about one train on one route on one day
+type ServiceRecord struct {
+	TrainUid          string `json:"train_uid"`
+	Date              string `json:"date"`
+	From              string `json:"from_code"`
+	To                string `json:"to_code"`
+	Operator          string `json:"operator"`
+	OperatorName      string `json:"operator_name"`
+	OriginName        string `json:"origin_name"`
+	DestinationName   string `json:"destination_name"`
+	AimedDeparture    string `json:"aimed_departure_time"`
+	DepartureDate     string `json:"aimed_departure_date,omitempty"`
+	ExpectedDeparture string `json:"expected_departure_time"`
+	FinalDeparture    string `json:"final_departure_time"`
+	AimedArrival      string `json:"aimed_arrival_time"`
+	ExpectedArrival   string `json:"expected_arrival_time"`
+	FinalArrival      string `json:"final_arrival_time"`
+	Status            string `json:"status"`
+	Cancelled         bool   `json:"cancelled"`
+	Platform          string `json:"platform"`
+	ArrivalPlatform   string `json:"arrival_platform"`
+	Snapshots         int    `json:"snapshots"`
+	FirstSeen         string `json:"first_seen"`
+	LastSeen          string `json:"last_seen"`
+}
+
+func (route Route) Bucket() []byte {
+	return []byte(route.From + ":" + route.To)
+}
+
+func recordKey(date string, train_uid string) []byte {
+	return []byte(date + "/" + train_uid)
+}
+
+// parseRoute turns "OXF:PAD" into a route with station names filled in
+func parseRoute(text string) Route {
+	from, to, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(text)), ":")
+	if !ok {
+		log.Fatalf("Invalid route %q, expected FROM:TO eg. OXF:PAD", text)
+	}
+	fromName, toName := validateInputs(from, to)
+	return Route{From: from, FromName: fromName, To: to, ToName: toName}
+}
+
+func readRoutes(args []string, routes_file string) []Route {
+	var routes []Route
+	for _, arg := range args {
+		routes = append(routes, parseRoute(arg))
+	}
+	if len(routes_file) > 0 {
+		f, err := os.Open(routes_file)
+		if err != nil {
+			log.Fatal("Cannot read routes: ", err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			routes = append(routes, parseRoute(line))
+		}
+	}
+	if len(routes) == 0 {
+		log.Fatal("No routes to record, give them as FROM:TO or with --routes")
+	}
+	return routes
+}
+
+func openHistory(db_file string, readOnly bool) *bolt.DB {
+	db, err := bolt.Open(db_file, 0600, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		log.Fatalf("Cannot open history %s: %v", db_file, err)
+	}
+	return db
+}
+
+// routeStops finds the stops for the start and end of the route in a trip
+func routeStops(trip TrainTrip) (*TrainStop, *TrainStop) {
+	var origin, dest *TrainStop
+	for i := range trip.Stops {
+		stop := &trip.Stops[i]
+		if origin == nil && stop.StationCode == trip.FromCode {
+			origin = stop
+		} else if origin != nil && stop.StationCode == trip.ToCode {
+			dest = stop
+			break
+		}
+	}
+	return origin, dest
+}
+
+// passed reports whether an expected time is now in the past
+func passed(date string, clock string, serviceDate string, now time.Time) bool {
+	t, ok := stopTime(date, clock, serviceDate)
+	return ok && !t.After(now)
+}
+
+var timetableDate = regexp.MustCompile(`/(\d{4}-\d{2}-\d{2})/timetable\.json`)
+
+// serviceDate is the date a train's service runs on, from its timetable, or the
+// link to it, or else its first stop.  boardDate is the last resort.
+func serviceDate(trip TrainTrip, boardDate string) string {
+	if len(trip.ServiceDate) > 0 {
+		return trip.ServiceDate
+	}
+	if m := timetableDate.FindStringSubmatch(trip.ServiceTimetable.Url); m != nil {
+		return m[1]
+	}
+	if len(trip.Stops) > 0 && len(trip.Stops[0].AimedDepartureDate) > 0 {
+		return trip.Stops[0].AimedDepartureDate
+	}
+	return boardDate
+}
+
+// callDate is the date of a call at clock, which is date if transportAPI gave us
+// one.  Otherwise it is worked out from an earlier call on fromDate at fromClock,
+// moving to the next day if clock is after midnight.
+func callDate(date string, clock string, fromDate string, fromClock string) string {
+	if len(date) > 0 {
+		return date
+	}
+	from, ok := clockMinutes(fromClock)
+	if !ok {
+		return fromDate
+	}
+	mins, ok := lateness(fromClock, clock)
+	if !ok {
+		return fromDate
+	}
+	t, err := time.Parse(DATE_FORMAT, fromDate)
+	if err != nil {
+		return fromDate
+	}
+	switch {
+	case from+mins >= MINUTES_PER_DAY:
+		t = t.AddDate(0, 0, 1)
+	case from+mins < 0:
+		t = t.AddDate(0, 0, -1)
+	}
+	return t.Format(DATE_FORMAT)
+}
+
+// update merges what one snapshot of the board says about a train into its record,
+// date being the date the service runs on
+func (record *ServiceRecord) update(trip TrainTrip, date string, now time.Time) {
+	record.TrainUid = trip.TrainUid
+	record.Date = date
+	record.From = trip.FromCode
+	record.To = trip.ToCode
+	record.Operator = trip.Operator
+	record.OperatorName = trip.OperatorName
+	record.OriginName = trip.OriginName
+	record.DestinationName = trip.DestinationName
+	record.AimedDeparture = trip.AimedDeparture
+	record.Status = trip.Status
+	record.Cancelled = record.Cancelled || strings.EqualFold(trip.Status, "CANCELLED")
+	if len(trip.Platform) > 0 {
+		record.Platform = trip.Platform
+	}
+	expectedDeparture := trip.ExpectedDeparture
+	expectedDate := ""
+	// The service starts on date, so a train starting late in the evening can reach
+	// the origin of the route on the next day
+	record.DepartureDate = date
+	origin, dest := routeStops(trip)
+	if origin != nil {
+		if len(origin.AimedDeparture) > 0 {
+			record.AimedDeparture = origin.AimedDeparture
+		}
+		if len(origin.ExpectedDeparture) > 0 {
+			expectedDeparture = origin.ExpectedDeparture
+			expectedDate = origin.ExpectedDepartureDate
+		}
+		record.DepartureDate = callDate(origin.AimedDepartureDate, record.AimedDeparture, date, scheduledTime(trip.Stops[0]))
+	}
+	if len(expectedDeparture) > 0 {
+		record.ExpectedDeparture = expectedDeparture
+		if passed(callDate(expectedDate, expectedDeparture, record.DepartureDate, record.AimedDeparture), expectedDeparture, date, now) {
+			record.FinalDeparture = expectedDeparture
+		}
+	}
+	if dest != nil {
+		record.AimedArrival = dest.AimedArrival
+		if len(dest.Platform) > 0 {
+			record.ArrivalPlatform = dest.Platform
+		}
+		if len(dest.ExpectedArrival) > 0 {
+			record.ExpectedArrival = dest.ExpectedArrival
+			arrivalDate := callDate(dest.ExpectedArrivalDate, dest.ExpectedArrival, record.DepartureDate, record.AimedDeparture)
+			if passed(arrivalDate, dest.ExpectedArrival, date, now) {
+				record.FinalArrival = dest.ExpectedArrival
+			}
+		}
+		record.Cancelled = record.Cancelled || strings.EqualFold(dest.Status, "CANCELLED")
+	}
+	stamp := now.Format(time.RFC3339)
+	if record.Snapshots == 0 {
+		record.FirstSeen = stamp
+	}
+	record.LastSeen = stamp
+	record.Snapshots++
+}
+
+// fetchTrips is getTrainsCallingAt followed by getTrainTrips returning any error
+// fetching the board.  Trains whose timetable cannot be fetched are left out.
+func fetchTrips(route Route) (TrainJourney, []TrainTrip, error) {
+	url := fmt.Sprintf("http://transportapi.com/v3/uk/train/station/%s/live.json", route.From)
+	journey, err := fetchTrainsCallingAt(url, route.From, route.To, route.ToName)
+	if err != nil {
+		return journey, nil, err
+	}
+	var trips []TrainTrip
+	for _, train := range journey.Departures.All {
+		service, err := fetchTrainService(train.ServiceTimetable.Url, route.From, route.To)
+		if err != nil {
+			slog.Warn("Cannot fetch timetable, leaving the train out", "train_uid", train.TrainUid, "error", err)
+			continue
+		}
+		trip := newTrainTrip(train, service.Stops, journey)
+		trip.ServiceDate = service.Date
+		trips = append(trips, trip)
+	}
+	return journey, trips, nil
+}
+
+// observation is one sighting of a train on the given date
+type observation struct {
+	date string
+	trip TrainTrip
+}
+
+// inTransit returns the recorded trains that have left the board but not yet been
+// seen arriving.  They are only followed for a couple of hours after they were due.
+func inTransit(db *bolt.DB, route Route, seen map[string]bool, now time.Time) []ServiceRecord {
+	var records []ServiceRecord
+	since := recordKey(now.AddDate(0, 0, -1).Format(DATE_FORMAT), "")
+	db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(route.Bucket())
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(since); k != nil; k, v = c.Next() {
+			record := ServiceRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				continue
+			}
+			if seen[record.TrainUid] || record.Cancelled || len(record.FinalArrival) > 0 {
+				continue
+			}
+			// Records from before we kept the departure date left on their service date
+			departureDate := firstNonEmpty(record.DepartureDate, record.Date)
+			due, ok := stopTime(callDate("", record.AimedArrival, departureDate, record.AimedDeparture), record.AimedArrival, record.Date)
+			if ok && due.Before(now) && due.Add(2*time.Hour).After(now) {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	return records
+}
+
+// followUp fetches the timetable for a train no longer on the board to see when it arrived
+func followUp(route Route, record ServiceRecord) (TrainTrip, error) {
+	url := fmt.Sprintf("http://transportapi.com/v3/uk/train/service/train_uid:%s/%s/timetable.json?app_id=%s&app_key=%s&live=true",
+		record.TrainUid, record.Date, APP_ID, APP_KEY)
+	stops, err := fetchTrainStops(url, route.From, route.To)
+	if err != nil {
+		return TrainTrip{}, err
+	}
+	train := TrainDeparture{
+		TrainUid:          record.TrainUid,
+		Operator:          record.Operator,
+		OperatorName:      record.OperatorName,
+		OriginName:        record.OriginName,
+		DestinationName:   record.DestinationName,
+		AimedDeparture:    record.AimedDeparture,
+		ExpectedDeparture: record.ExpectedDeparture,
+		Platform:          record.Platform,
+		Status:            record.Status,
+	}
+	return newTrainTrip(train, stops, TrainJourney{StationCode: route.From, DestinationCode: route.To}), nil
+}
+
+// snapshot records the current board for one route along with the arrival of any
+// trains that have left it since the last snapshot, noting their platforms in
+// platforms_file
+func snapshot(db *bolt.DB, platforms_file string, route Route, now time.Time) (int, error) {
+	journey, trips, err := fetchTrips(route)
+	if err != nil {
+		return 0, err
+	}
+	date := journey.Date
+	if len(date) == 0 {
+		date = now.Format(DATE_FORMAT)
+	}
+	var observations []observation
+	seen := make(map[string]bool)
+	for _, trip := range trips {
+		observations = append(observations, observation{date: serviceDate(trip, date), trip: trip})
+		seen[trip.TrainUid] = true
+	}
+	learnPlatforms(platforms_file, trips, date)
+	for _, record := range inTransit(db, route, seen, now) {
+		trip, err := followUp(route, record)
+		if err != nil {
+			slog.Warn("Follow up failed", "train_uid", record.TrainUid, "date", record.Date, "error", err)
+			continue
+		}
+		observations = append(observations, observation{date: record.Date, trip: trip})
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(route.Bucket())
+		if err != nil {
+			return err
+		}
+		for _, observed := range observations {
+			key := recordKey(observed.date, observed.trip.TrainUid)
+			record := ServiceRecord{}
+			if data := bucket.Get(key); data != nil {
+				if err := json.Unmarshal(data, &record); err != nil {
+					return err
+				}
+			}
+			record.update(observed.trip, observed.date, now)
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(trips), err
+}
+
+func recordRoutes(routes []Route, db_file string, platforms_file string, interval time.Duration, once bool) {
+	db := openHistory(db_file, false)
+	defer db.Close()
+	for {
+		for _, route := range routes {
+			now := time.Now()
+			n, err := snapshot(db, platforms_file, route, now)
+			if err != nil {
+				slog.Warn("Snapshot failed", "route", string(route.Bucket()), "error", err)
+				continue
+			}
+			fmt.Println(fmt.Sprintf("%s recorded %d trains from %s to %s", now.Format("15:04:05"), n, route.FromName, route.ToName))
+		}
+		if once {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
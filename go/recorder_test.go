@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func at(clock string, date string) time.Time {
+	t, err := time.ParseInLocation(DATE_FORMAT+" 15:04", date+" "+clock, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestServiceDate(t *testing.T) {
+	withUrl := TrainTrip{}
+	withUrl.ServiceTimetable.Url = "http://transportapi.com/v3/uk/train/service/train_uid:C23294/2019-10-27/timetable.json?live=true"
+	tests := []struct {
+		name string
+		trip TrainTrip
+		want string
+	}{
+		{"from the timetable", TrainTrip{ServiceDate: "2019-10-27"}, "2019-10-27"},
+		{"from the timetable link", withUrl, "2019-10-27"},
+		{"from the first stop", TrainTrip{Stops: []TrainStop{{AimedDepartureDate: "2019-10-27"}}}, "2019-10-27"},
+		{"from the board", TrainTrip{}, "2019-10-26"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceDate(tt.trip, "2019-10-26"); got != tt.want {
+				t.Errorf("serviceDate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallDate(t *testing.T) {
+	tests := []struct {
+		date      string
+		clock     string
+		fromClock string
+		want      string
+	}{
+		{"2019-10-28", "00:20", "23:40", "2019-10-28"},
+		{"", "10:20", "09:40", "2019-10-26"},
+		{"", "00:20", "23:40", "2019-10-27"},
+		{"", "23:59", "00:01", "2019-10-25"},
+		{"", "00:20", "", "2019-10-26"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fromClock+"-"+tt.clock, func(t *testing.T) {
+			if got := callDate(tt.date, tt.clock, "2019-10-26", tt.fromClock); got != tt.want {
+				t.Errorf("callDate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceRecordUpdateAroundMidnight(t *testing.T) {
+	trip := func(date string, stops ...TrainStop) TrainTrip {
+		trip := TrainTrip{Stops: stops, FromCode: "OXF", ToCode: "PAD", ServiceDate: date}
+		trip.TrainUid = "C1"
+		return trip
+	}
+	beforeMidnight := trip("2019-10-26",
+		TrainStop{StationCode: "OXF", AimedDeparture: "23:40", ExpectedDeparture: "23:42"},
+		TrainStop{StationCode: "PAD", AimedArrival: "00:20", ExpectedArrival: "00:25"})
+	afterMidnight := trip("2019-10-27",
+		TrainStop{StationCode: "OXF", AimedDeparture: "00:05", ExpectedDeparture: "00:05"},
+		TrainStop{StationCode: "PAD", AimedArrival: "00:55", ExpectedArrival: "00:55"})
+	startedBefore := trip("2019-10-26",
+		TrainStop{StationCode: "BHM", AimedDeparture: "22:30"},
+		TrainStop{StationCode: "OXF", AimedDeparture: "00:05", ExpectedDeparture: "00:07"},
+		TrainStop{StationCode: "PAD", AimedArrival: "00:55", ExpectedArrival: "00:58"})
+	tests := []struct {
+		name          string
+		trip          TrainTrip
+		now           time.Time
+		departureDate string
+		finalDep      string
+		finalArr      string
+	}{
+		{"board before midnight, departed, not arrived", beforeMidnight, at("23:50", "2019-10-26"), "2019-10-26", "23:42", ""},
+		{"board after midnight, arrived", beforeMidnight, at("00:30", "2019-10-27"), "2019-10-26", "23:42", "00:25"},
+		{"board before midnight, train tomorrow", afterMidnight, at("23:50", "2019-10-26"), "2019-10-27", "", ""},
+		{"board after midnight, train departed", afterMidnight, at("00:10", "2019-10-27"), "2019-10-27", "00:05", ""},
+		{"board before midnight, started yesterday evening", startedBefore, at("23:50", "2019-10-26"), "2019-10-27", "", ""},
+		{"board after midnight, started yesterday evening", startedBefore, at("01:00", "2019-10-27"), "2019-10-27", "00:07", "00:58"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := ServiceRecord{}
+			date := serviceDate(tt.trip, tt.now.Format(DATE_FORMAT))
+			record.update(tt.trip, date, tt.now)
+			if record.Date != tt.trip.ServiceDate || record.DepartureDate != tt.departureDate {
+				t.Errorf("dates %s and %s, want %s and %s", record.Date, record.DepartureDate, tt.trip.ServiceDate, tt.departureDate)
+			}
+			if record.FinalDeparture != tt.finalDep || record.FinalArrival != tt.finalArr {
+				t.Errorf("final times %q and %q, want %q and %q", record.FinalDeparture, record.FinalArrival, tt.finalDep, tt.finalArr)
+			}
+		})
+	}
+}
+
+func TestInTransitAfterMidnight(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "history.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	route := Route{From: "OXF", To: "PAD"}
+	records := []ServiceRecord{
+		{TrainUid: "LATE", Date: "2019-10-26", DepartureDate: "2019-10-26", AimedDeparture: "23:40", AimedArrival: "00:20"},
+		// Recorded before the departure date was kept
+		{TrainUid: "OLD", Date: "2019-10-26", AimedDeparture: "23:45", AimedArrival: "00:25"},
+		{TrainUid: "ARRIVED", Date: "2019-10-26", AimedDeparture: "23:30", AimedArrival: "00:10", FinalArrival: "00:12"},
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(route.Bucket())
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			data, _ := json.Marshal(record)
+			if err := bucket.Put(recordKey(record.Date, record.TrainUid), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		now  time.Time
+		want []string
+	}{
+		{at("23:55", "2019-10-26"), nil},
+		{at("00:30", "2019-10-27"), []string{"LATE", "OLD"}},
+		{at("03:00", "2019-10-27"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.now.Format(time.RFC3339), func(t *testing.T) {
+			var got []string
+			for _, record := range inTransit(db, route, map[string]bool{}, tt.now) {
+				got = append(got, record.TrainUid)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("in transit %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("in transit %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ roundtrip.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Utility module to show commuters both legs of a round trip together.
+The outbound board is the live board from A to B.  The return board from B to A
+is either live too or, when a later time is given, taken from the scheduled
+station timetable for a window starting at that time today.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+type TrainRoundTrip struct {
+	Outbound TrainBoard `json:"outbound"`
+	Return   TrainBoard `json:"return"`
+}
+
+func getReturnJourney(stationCode string, stationName string, destCode string, destName string, back string) TrainJourney {
+	// The return leg runs from the destination back to the origin
+	if len(back) == 0 {
+		return getTrainsCallingAt(destCode, destName, stationCode, stationName)
+	}
+	if _, ok := clockMinutes(back); !ok {
+		log.Fatal(`Invalid return time, expected HH:MM`)
+	}
+	date := time.Now().Format(DATE_FORMAT)
+	return getTrainsCallingAtTime(destCode, destName, stationCode, stationName, date, back)
+}
+
+func formatRoundTrip(outbound TrainJourney, outTrips []TrainTrip, back TrainJourney, backTrips []TrainTrip, format string) {
+	if format != "json" {
+		formatTrains(outbound, outTrips, format)
+		formatTrains(back, backTrips, format)
+		return
+	}
+	roundTrip := TrainRoundTrip{
+		Outbound: newTrainBoard(outbound, outTrips),
+		Return:   newTrainBoard(back, backTrips),
+	}
+	data, err := json.MarshalIndent(roundTrip, "", "  ")
+	if err != nil {
+		log.Fatal("Cannot serialize JSON: ", err)
+	}
+	fmt.Println(string(data))
+}
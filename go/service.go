@@ -0,0 +1,206 @@
+/*
+ service.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Utility module to drill into a single train using its train_uid.
+The service timetable returned by transportAPI lists every timing point the train
+passes or calls at along with aimed and expected times, platform and status.
+With live=true the expected times are updated as the train reports in, which lets
+us work out roughly where the train currently is.
+
+Installation
+------------
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	grequests "github.com/levigross/grequests"
+)
+
+const DATE_FORMAT = "2006-01-02"
+
+type TrainService struct {
+	TrainStops
+	CurrentStationCode string `json:"current_station_code,omitempty"`
+}
+
+func getServiceTimetable(trainUid string, date string) TrainStops {
+	service, err := fetchServiceTimetable(trainUid, date)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return service
+}
+
+// fetchServiceTimetable is getServiceTimetable returning any error
+func fetchServiceTimetable(trainUid string, date string) (TrainStops, error) {
+	url := fmt.Sprintf("http://transportapi.com/v3/uk/train/service/train_uid:%s/%s/timetable.json", trainUid, date)
+	params := make(map[string]string)
+	params["app_id"] = APP_ID
+	params["app_key"] = APP_KEY
+	params["live"] = "true"
+
+	service := &TrainStops{}
+	resp, err := grequests.Get(url, &grequests.RequestOptions{Params: params})
+	if err != nil {
+		return *service, fmt.Errorf("Unable to make service request: %v", err)
+	}
+	respStr := resp.String()
+	debugResponse(resp, respStr)
+	if !resp.Ok {
+		return *service, fmt.Errorf("Service request failed with status %d", resp.StatusCode)
+	}
+	if err := resp.JSON(service); err != nil {
+		return *service, fmt.Errorf("Cannot serialize JSON: %v", err)
+	}
+	slog.Debug("Service", "service", fmt.Sprintf("%+v", service))
+	return *service, nil
+}
+
+// stopTime combines a stop date and "HH:MM" time, falling back to the service date
+func stopTime(date string, clock string, serviceDate string) (time.Time, bool) {
+	if len(clock) == 0 {
+		return time.Time{}, false
+	}
+	if len(date) == 0 {
+		date = serviceDate
+	}
+	t, err := time.ParseInLocation(DATE_FORMAT+" 15:04", date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// currentStopIndex returns the index of the last stop the train has reached according
+// to its live expected times, or -1 when there is no live data to go on.
+func currentStopIndex(service TrainStops, now time.Time) int {
+	current := -1
+	live := false
+	for i, stop := range service.Stops {
+		var t time.Time
+		var ok bool
+		if len(stop.ExpectedDeparture) > 0 {
+			t, ok = stopTime(stop.ExpectedDepartureDate, stop.ExpectedDeparture, service.Date)
+		} else if len(stop.ExpectedPass) > 0 {
+			t, ok = stopTime(stop.ExpectedPassDate, stop.ExpectedPass, service.Date)
+		} else if len(stop.ExpectedArrival) > 0 {
+			t, ok = stopTime(stop.ExpectedArrivalDate, stop.ExpectedArrival, service.Date)
+		}
+		if !ok {
+			continue
+		}
+		live = true
+		if !t.After(now) {
+			current = i
+		}
+	}
+	if !live {
+		return -1
+	}
+	return current
+}
+
+func formatServiceHeader(service TrainStops) string {
+	header := fmt.Sprintf("==== Train %s (%s) from %s to %s", service.TrainUid, service.Operator, service.OriginName, service.DestinationName)
+	header += fmt.Sprintf(" %s ====", service.Date)
+	return header
+}
+
+func formatStopTimes(aimed string, expected string) string {
+	if len(aimed) == 0 {
+		return ""
+	}
+	if len(expected) == 0 || expected == aimed {
+		return aimed
+	}
+	return fmt.Sprintf("%s (%s)", aimed, expected)
+}
+
+func formatServiceStop(stop TrainStop, current bool) string {
+	marker := "  "
+	if current {
+		marker = "=>"
+	}
+	line := fmt.Sprintf("%s %s (%s)", marker, stop.StationName, stop.StationCode)
+	if stop.IsPass() {
+		line += fmt.Sprintf(" passes %s", formatStopTimes(stop.AimedPass, stop.ExpectedPass))
+	} else {
+		if arr := formatStopTimes(stop.AimedArrival, stop.ExpectedArrival); len(arr) > 0 {
+			line += fmt.Sprintf(" arr %s", arr)
+		}
+		if dep := formatStopTimes(stop.AimedDeparture, stop.ExpectedDeparture); len(dep) > 0 {
+			line += fmt.Sprintf(" dep %s", dep)
+		}
+		if len(stop.Platform) > 0 || stop.LikelyPlatform != nil {
+			line += " " + formatPlatform(stop)
+		}
+	}
+	if len(stop.Status) > 0 {
+		line += fmt.Sprintf(" => %s", stop.Status)
+	}
+	return line
+}
+
+func printServiceTable(service TrainStops, current int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tSTATION\tCODE\tARR\tEXP ARR\tDEP\tEXP DEP\tPLAT\tSTATUS")
+	for i, stop := range service.Stops {
+		marker := ""
+		if i == current {
+			marker = "=>"
+		}
+		arr, expArr := stop.AimedArrival, stop.ExpectedArrival
+		dep, expDep := stop.AimedDeparture, stop.ExpectedDeparture
+		if stop.IsPass() {
+			dep, expDep = "pass "+stop.AimedPass, stop.ExpectedPass
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", marker, stop.StationName, stop.StationCode,
+			arr, expArr, dep, expDep, tablePlatform(stop), stop.Status)
+	}
+	w.Flush()
+}
+
+func formatService(service TrainStops, format string) {
+	current := currentStopIndex(service, time.Now())
+	switch format {
+	case "json":
+		detail := TrainService{TrainStops: service}
+		if current >= 0 {
+			detail.CurrentStationCode = service.Stops[current].StationCode
+		}
+		data, err := json.MarshalIndent(detail, "", "  ")
+		if err != nil {
+			log.Fatal("Cannot serialize JSON: ", err)
+		}
+		fmt.Print(string(data))
+	case "table":
+		printHeader(formatServiceHeader(service))
+		printServiceTable(service, current)
+	default:
+		printHeader(formatServiceHeader(service))
+		for i, stop := range service.Stops {
+			fmt.Println(formatServiceStop(stop, i == current))
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,322 @@
+/*
+ stats.go
+(c) 2019 Mal Minhas, <mal@malm.co.uk>
+
+Licence
+--------
+Copyright 2019 Mal Minhas. All Rights Reserved.
+
+Description
+-----------
+Punctuality reports from the history kept by the record command.
+For the last --days days of a route we report, overall and for each scheduled
+departure time, day of the week and operator, the share of trains on time, the
+average and 90th percentile delay and the share cancelled, followed by the trains
+that are most often late.  Delays are measured at the destination using the final
+arrival time, or the last expected one if the train was never seen arriving, and
+fall back to the departure if there is no arrival time at all.  As with the
+industry's PPM measure a train is on time if it is less than 5 minutes late.
+Early running counts as no delay.
+
+Installation
+------------
+$ go run . stats OXF PAD --days=30 --format=csv > oxf-pad.csv
+
+Version
+-------
+19.10.26  0.1   First version
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const ON_TIME_MINS = 5
+const WORST_TRAINS = 5
+
+type PunctualitySummary struct {
+	Key          string  `json:"key"`
+	Description  string  `json:"description,omitempty"`
+	Trains       int     `json:"trains"`
+	Known        int     `json:"known_trains"`
+	OnTimePct    float64 `json:"on_time_pct"`
+	AverageDelay float64 `json:"average_delay_mins"`
+	P90Delay     int     `json:"p90_delay_mins"`
+	CancelledPct float64 `json:"cancelled_pct"`
+}
+
+type PunctualityReport struct {
+	FromCode    string               `json:"from_code"`
+	FromName    string               `json:"from_name"`
+	ToCode      string               `json:"to_code"`
+	ToName      string               `json:"to_name"`
+	Since       string               `json:"since"`
+	Until       string               `json:"until"`
+	Overall     PunctualitySummary   `json:"overall"`
+	Departures  []PunctualitySummary `json:"departures"`
+	WorstTrains []PunctualitySummary `json:"worst_trains"`
+	DaysOfWeek  []PunctualitySummary `json:"days_of_week"`
+	Operators   []PunctualitySummary `json:"operators"`
+}
+
+func orExpected(final string, expected string) string {
+	if len(final) > 0 {
+		return final
+	}
+	return expected
+}
+
+// Delay returns the minutes late a recorded train was, measured at the destination if possible
+func (record ServiceRecord) Delay() (int, bool) {
+	if mins, ok := lateness(record.AimedArrival, orExpected(record.FinalArrival, record.ExpectedArrival)); ok {
+		return mins, true
+	}
+	return lateness(record.AimedDeparture, orExpected(record.FinalDeparture, record.ExpectedDeparture))
+}
+
+// readHistory returns the records for a route from the given date onwards
+func readHistory(db_file string, route Route, since string) []ServiceRecord {
+	db := openHistory(db_file, true)
+	defer db.Close()
+	var records []ServiceRecord
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(route.Bucket())
+		if bucket == nil {
+			return fmt.Errorf("No history for %s to %s, record some with: record %s", route.FromName, route.ToName, route.Bucket())
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(recordKey(since, "")); k != nil; k, v = c.Next() {
+			record := ServiceRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return records
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(1000*float64(n)/float64(total)) / 10
+}
+
+func summarise(key string, records []ServiceRecord) PunctualitySummary {
+	summary := PunctualitySummary{Key: key, Trains: len(records)}
+	var delays []int
+	cancelled, onTime, total := 0, 0, 0
+	for _, record := range records {
+		if record.Cancelled {
+			cancelled++
+			continue
+		}
+		delay, ok := record.Delay()
+		if !ok {
+			continue
+		}
+		if delay < 0 {
+			delay = 0
+		}
+		if delay < ON_TIME_MINS {
+			onTime++
+		}
+		delays = append(delays, delay)
+		total += delay
+	}
+	summary.CancelledPct = percent(cancelled, len(records))
+	// Cancelled trains count against being on time
+	summary.Known = len(delays) + cancelled
+	summary.OnTimePct = percent(onTime, summary.Known)
+	if len(delays) > 0 {
+		sort.Ints(delays)
+		summary.AverageDelay = math.Round(10*float64(total)/float64(len(delays))) / 10
+		summary.P90Delay = delays[int(math.Ceil(0.9*float64(len(delays))))-1]
+	}
+	return summary
+}
+
+// summariseBy groups records by key and summarises each group in key order
+func summariseBy(records []ServiceRecord, key func(ServiceRecord) string) []PunctualitySummary {
+	groups := make(map[string][]ServiceRecord)
+	for _, record := range records {
+		k := key(record)
+		groups[k] = append(groups[k], record)
+	}
+	var summaries []PunctualitySummary
+	for k, group := range groups {
+		summaries = append(summaries, summarise(k, group))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Key < summaries[j].Key })
+	return summaries
+}
+
+func weekday(record ServiceRecord) time.Weekday {
+	t, err := time.Parse(DATE_FORMAT, record.Date)
+	if err != nil {
+		return -1
+	}
+	return t.Weekday()
+}
+
+func newPunctualityReport(route Route, records []ServiceRecord, since string, until string) PunctualityReport {
+	report := PunctualityReport{
+		FromCode: route.From,
+		FromName: route.FromName,
+		ToCode:   route.To,
+		ToName:   route.ToName,
+		Since:    since,
+		Until:    until,
+		Overall:  summarise("all", records),
+	}
+	report.Departures = summariseBy(records, func(r ServiceRecord) string { return r.AimedDeparture })
+	report.Operators = summariseBy(records, func(r ServiceRecord) string { return r.OperatorName })
+
+	// Monday first rather than Sunday
+	days := summariseBy(records, func(r ServiceRecord) string { return strconv.Itoa((int(weekday(r)) + 6) % 7) })
+	for i := range days {
+		n, _ := strconv.Atoi(days[i].Key)
+		days[i].Key = time.Weekday((n + 1) % 7).String()
+	}
+	report.DaysOfWeek = days
+
+	described := make(map[string]string)
+	for _, record := range records {
+		described[record.TrainUid] = fmt.Sprintf("%s %s", record.AimedDeparture, record.OperatorName)
+	}
+	// Trains never seen to depart or arrive can't be ranked
+	var worst []PunctualitySummary
+	for _, summary := range summariseBy(records, func(r ServiceRecord) string { return r.TrainUid }) {
+		if summary.Known > 0 {
+			worst = append(worst, summary)
+		}
+	}
+	sort.SliceStable(worst, func(i, j int) bool {
+		if worst[i].OnTimePct != worst[j].OnTimePct {
+			return worst[i].OnTimePct < worst[j].OnTimePct
+		}
+		return worst[i].AverageDelay > worst[j].AverageDelay
+	})
+	if len(worst) > WORST_TRAINS {
+		worst = worst[:WORST_TRAINS]
+	}
+	for i := range worst {
+		worst[i].Description = described[worst[i].Key]
+	}
+	report.WorstTrains = worst
+	return report
+}
+
+func getPunctualityReport(stationCode string, stationName string, destCode string, destName string, days int, db_file string) PunctualityReport {
+	if days < 1 {
+		log.Fatal("--days must be at least 1")
+	}
+	route := Route{From: stationCode, FromName: stationName, To: destCode, ToName: destName}
+	now := time.Now()
+	since := now.AddDate(0, 0, 1-days).Format(DATE_FORMAT)
+	records := readHistory(db_file, route, since)
+	return newPunctualityReport(route, records, since, now.Format(DATE_FORMAT))
+}
+
+func formatPunctualityHeader(report PunctualityReport) string {
+	header := fmt.Sprintf("==== Punctuality from %s (%s) to %s (%s)", report.FromName, report.FromCode, report.ToName, report.ToCode)
+	header += fmt.Sprintf(" %s to %s ====", report.Since, report.Until)
+	return header
+}
+
+// onTime is the on time percentage, or n/a if no train's punctuality is known
+func onTime(summary PunctualitySummary) string {
+	if summary.Known == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", summary.OnTimePct)
+}
+
+func formatSummary(summary PunctualitySummary) string {
+	return fmt.Sprintf("%d trains, %s on time, average delay %.1f mins, p90 %d mins, %.1f%% cancelled",
+		summary.Trains, onTime(summary), summary.AverageDelay, summary.P90Delay, summary.CancelledPct)
+}
+
+func printSummaryTable(title string, summaries []PunctualitySummary) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tTRAINS\tON TIME\tAVG DELAY\tP90\tCANCELLED\t\n", title)
+	for _, s := range summaries {
+		key := s.Key
+		if len(s.Description) > 0 {
+			key += " " + s.Description
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.1f\t%d\t%.1f%%\t\n", key, s.Trains, onTime(s), s.AverageDelay, s.P90Delay, s.CancelledPct)
+	}
+	w.Flush()
+}
+
+func printPunctualityCSV(report PunctualityReport) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"section", "key", "description", "trains", "on_time_pct", "average_delay_mins", "p90_delay_mins", "cancelled_pct"})
+	sections := []struct {
+		name      string
+		summaries []PunctualitySummary
+	}{
+		{"overall", []PunctualitySummary{report.Overall}},
+		{"departure", report.Departures},
+		{"worst_train", report.WorstTrains},
+		{"day_of_week", report.DaysOfWeek},
+		{"operator", report.Operators},
+	}
+	for _, section := range sections {
+		for _, s := range section.summaries {
+			// Left empty rather than 0 when no train's punctuality is known
+			onTimePct := ""
+			if s.Known > 0 {
+				onTimePct = strconv.FormatFloat(s.OnTimePct, 'f', 1, 64)
+			}
+			w.Write([]string{section.name, s.Key, s.Description, strconv.Itoa(s.Trains),
+				onTimePct, strconv.FormatFloat(s.AverageDelay, 'f', 1, 64),
+				strconv.Itoa(s.P90Delay), strconv.FormatFloat(s.CancelledPct, 'f', 1, 64)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatal("Cannot write CSV: ", err)
+	}
+}
+
+func formatPunctuality(report PunctualityReport, format string) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal("Cannot serialize JSON: ", err)
+		}
+		fmt.Println(string(data))
+	case "csv":
+		printPunctualityCSV(report)
+	default:
+		printHeader(formatPunctualityHeader(report))
+		fmt.Println(formatSummary(report.Overall))
+		printSummaryTable("DEPARTURE", report.Departures)
+		printSummaryTable("WORST TRAINS", report.WorstTrains)
+		printSummaryTable("DAY", report.DaysOfWeek)
+		printSummaryTable("OPERATOR", report.Operators)
+		fmt.Println()
+	}
+}
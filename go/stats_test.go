@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// arrived is a record for a train due at 10:00 that arrived delay minutes late
+func arrived(delay int) ServiceRecord {
+	return ServiceRecord{AimedArrival: "10:00", FinalArrival: fmt.Sprintf("%02d:%02d", 10+delay/60, delay%60)}
+}
+
+func TestSummarise(t *testing.T) {
+	var tenDelays []ServiceRecord
+	for delay := 1; delay <= 10; delay++ {
+		tenDelays = append(tenDelays, arrived(delay))
+	}
+	tests := []struct {
+		name    string
+		records []ServiceRecord
+		want    PunctualitySummary
+	}{
+		{"none", nil, PunctualitySummary{Key: "k"}},
+		{"one on time", []ServiceRecord{arrived(0)},
+			PunctualitySummary{Key: "k", Trains: 1, Known: 1, OnTimePct: 100}},
+		// ceil(0.9 * 10) = 9th delay
+		{"p90 of ten", tenDelays,
+			PunctualitySummary{Key: "k", Trains: 10, Known: 10, OnTimePct: 40, AverageDelay: 5.5, P90Delay: 9}},
+		{"p90 of one", []ServiceRecord{arrived(12)},
+			PunctualitySummary{Key: "k", Trains: 1, Known: 1, AverageDelay: 12, P90Delay: 12}},
+		{"early counts as on time", []ServiceRecord{{AimedArrival: "10:00", FinalArrival: "09:58"}, arrived(8)},
+			PunctualitySummary{Key: "k", Trains: 2, Known: 2, OnTimePct: 50, AverageDelay: 4, P90Delay: 8}},
+		{"cancelled is not on time", []ServiceRecord{arrived(1), arrived(2), {Cancelled: true}},
+			PunctualitySummary{Key: "k", Trains: 3, Known: 3, OnTimePct: 66.7, AverageDelay: 1.5, P90Delay: 2, CancelledPct: 33.3}},
+		{"late past midnight", []ServiceRecord{{AimedArrival: "23:55", FinalArrival: "00:10"}},
+			PunctualitySummary{Key: "k", Trains: 1, Known: 1, AverageDelay: 15, P90Delay: 15}},
+		{"departure when there is no arrival", []ServiceRecord{{AimedDeparture: "10:00", ExpectedDeparture: "10:03"}},
+			PunctualitySummary{Key: "k", Trains: 1, Known: 1, OnTimePct: 100, AverageDelay: 3, P90Delay: 3}},
+		{"no times", []ServiceRecord{{TrainUid: "C1"}},
+			PunctualitySummary{Key: "k", Trains: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarise("k", tt.records); got != tt.want {
+				t.Errorf("summarise() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorstTrains(t *testing.T) {
+	late := func(uid string, delay int) ServiceRecord {
+		record := arrived(delay)
+		record.TrainUid = uid
+		return record
+	}
+	records := []ServiceRecord{
+		late("C1", 0), late("C1", 2),
+		late("C2", 8), late("C2", 1),
+		late("C3", 12),
+		// Never seen to depart or arrive
+		{TrainUid: "C4", AimedArrival: "10:00"},
+		{TrainUid: "C5", Cancelled: true},
+	}
+	report := newPunctualityReport(Route{From: "OXF", To: "PAD"}, records, "2019-10-20", "2019-10-26")
+	var got []string
+	for _, summary := range report.WorstTrains {
+		got = append(got, summary.Key)
+	}
+	// C3 and the cancelled C5 are never on time so the longer delay comes first
+	if want := "[C3 C5 C2 C1]"; fmt.Sprint(got) != want {
+		t.Errorf("worst trains %v, want %s", got, want)
+	}
+	if got := onTime(summarise("C4", records[5:6])); got != "n/a" {
+		t.Errorf("on time for a train with no delays = %s, want n/a", got)
+	}
+	if got := onTime(report.WorstTrains[1]); got != "0.0%" {
+		t.Errorf("on time for a cancelled train = %s, want 0.0%%", got)
+	}
+}
@@ -41,10 +41,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
-
-	//"time"
+	"text/tabwriter"
+	"time"
 
 	docopt "github.com/docopt/docopt-go"
 	grequests "github.com/levigross/grequests"
@@ -59,17 +60,41 @@ var APP_ID = ""
 var APP_KEY = ""
 
 type TrainStop struct {
-	StationCode     string `json:"station_code"`
-	StationName     string `json:"station_name"`
-	ExpectedArrival string `json:"expected_arrival_time"`
-	Platform        string `json:"platform"`
-	OnRoute         bool   `json:"on_route"`
+	StationCode           string `json:"station_code"`
+	TiplocCode            string `json:"tiploc_code"`
+	StationName           string `json:"station_name"`
+	StopType              string `json:"stop_type"`
+	Platform              string `json:"platform"`
+	AimedDepartureDate    string `json:"aimed_departure_date"`
+	AimedDeparture        string `json:"aimed_departure_time"`
+	AimedArrivalDate      string `json:"aimed_arrival_date"`
+	AimedArrival          string `json:"aimed_arrival_time"`
+	AimedPassDate         string `json:"aimed_pass_date"`
+	AimedPass             string `json:"aimed_pass_time"`
+	ExpectedDepartureDate string `json:"expected_departure_date"`
+	ExpectedDeparture     string `json:"expected_departure_time"`
+	ExpectedArrivalDate   string `json:"expected_arrival_date"`
+	ExpectedArrival       string `json:"expected_arrival_time"`
+	ExpectedPassDate      string `json:"expected_pass_date"`
+	ExpectedPass          string `json:"expected_pass_time"`
+	Status                string `json:"status"`
+	OnRoute               bool   `json:"on_route"`
+	// LikelyPlatform is filled in from platform history when Platform is empty
+	LikelyPlatform *PlatformPrediction `json:"likely_platform,omitempty"`
 }
 
 type TrainStops struct {
-	Service  string      `json:"service"`
-	TrainUid string      `json:"train_uid"`
-	Stops    []TrainStop `json:"stops"`
+	Service         string      `json:"service"`
+	TrainUid        string      `json:"train_uid"`
+	Headcode        string      `json:"headcode"`
+	TrainStatus     string      `json:"train_status"`
+	OriginName      string      `json:"origin_name"`
+	DestinationName string      `json:"destination_name"`
+	Date            string      `json:"date"`
+	Category        string      `json:"category"`
+	Operator        string      `json:"operator"`
+	OperatorName    string      `json:"operator_name"`
+	Stops           []TrainStop `json:"stops"`
 }
 
 /*
@@ -172,6 +197,18 @@ type TrainJourney struct {
 	Departures      TrainDepartures `json:"departures"`
 	DestinationName string          `json:"destination_name"`
 	DestinationCode string          `json:"destination_code"`
+	TimetableOnly   bool            `json:"timetable_only,omitempty"`
+}
+
+type TrainBoard struct {
+	Date            string      `json:"date"`
+	TimeOfDay       string      `json:"time_of_day"`
+	StationName     string      `json:"station_name"`
+	StationCode     string      `json:"station_code"`
+	DestinationName string      `json:"destination_name"`
+	DestinationCode string      `json:"destination_code"`
+	TimetableOnly   bool        `json:"timetable_only,omitempty"`
+	Trains          []TrainTrip `json:"trains"`
 }
 
 // ---------- code -----------
@@ -211,56 +248,105 @@ func readCred(fname string) string {
 	return value
 }
 
-func getTrainsCallingAt(station_code string, station_name string, dest_code string, dest_name string, verbose bool) TrainJourney {
+func getTrainsCallingAt(station_code string, station_name string, dest_code string, dest_name string) TrainJourney {
 	// station_code is 3 letter string.  eg. 'TWY','PAD'
 	// from_offset is one hour in past by default
 	// to_offset is two hours into future by default
 	// type can be arrival|departure|pass
 	url := fmt.Sprintf("http://transportapi.com/v3/uk/train/station/%s/live.json", station_code)
+	return requestTrainsCallingAt(url, station_code, dest_code, dest_name)
+}
+
+func getTrainsCallingAtTime(station_code string, station_name string, dest_code string, dest_name string, date string, clock string) TrainJourney {
+	// Same as getTrainsCallingAt but for a window starting at a given date and "HH:MM" time.
+	// This uses the scheduled station timetable so there are no live estimates.
+	url := fmt.Sprintf("http://transportapi.com/v3/uk/train/station/%s/%s/%s/timetable.json", station_code, date, clock)
+	return requestTrainsCallingAt(url, station_code, dest_code, dest_name)
+}
+
+func requestTrainsCallingAt(url string, station_code string, dest_code string, dest_name string) TrainJourney {
+	journey, err := fetchTrainsCallingAt(url, station_code, dest_code, dest_name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return journey
+}
+
+// fetchTrainsCallingAt is requestTrainsCallingAt returning any error rather than
+// exiting, for long running commands such as record
+func fetchTrainsCallingAt(url string, station_code string, dest_code string, dest_name string) (TrainJourney, error) {
 	params := make(map[string]string)
 	params["app_id"] = APP_ID
 	params["app_key"] = APP_KEY
 	params["station_code"] = station_code
-	params["calling_at"] = dest_code
+	if len(dest_code) > 0 {
+		params["calling_at"] = dest_code
+	}
 	params["type"] = "departure"
 
 	// You can modify the request by passing an optional RequestOptions struct
 	resp, err := grequests.Get(url, &grequests.RequestOptions{Params: params})
 	if err != nil {
-		log.Fatalln("Unable to make journey request: ", err)
+		return TrainJourney{}, fmt.Errorf("Unable to make journey request: %v", err)
 	}
 	respStr := resp.String()
+	debugResponse(resp, respStr)
+	if !resp.Ok {
+		return TrainJourney{}, fmt.Errorf("Journey request failed with status %d", resp.StatusCode)
+	}
 	journey := &TrainJourney{}
 	if err := resp.JSON(journey); err != nil {
-		log.Fatal("Cannot serialize JSON: ", err)
+		return TrainJourney{}, fmt.Errorf("Cannot serialize JSON: %v", err)
 	}
 	// Fill in these two values
 	journey.DestinationName = dest_name
 	journey.DestinationCode = dest_code
-	if verbose {
-		fmt.Println(fmt.Sprintf("Base URL: %s", url))
-		pdata, _ := json.Marshal(params)
-		fmt.Println(fmt.Sprintf("Params: %s", string(pdata)))
-		fmt.Println(fmt.Sprintf("Response:\n%s", respStr))
-		fmt.Println(fmt.Sprintf("Journey:\n%+v", journey))
+	slog.Debug("Journey", "journey", fmt.Sprintf("%+v", journey))
+	return *journey, nil
+}
+
+func StopProducer(timetable_url string, station_code string, dest_code string, ch chan<- []TrainStop) {
+	arr, err := fetchTrainStops(timetable_url, station_code, dest_code)
+	if err != nil {
+		log.Fatal(err)
 	}
-	return *journey
+	ch <- arr
 }
 
-func StopProducer(timetable_url string, station_code string, dest_code string, verbose bool, ch chan<- []TrainStop) {
-	var arr []TrainStop
+// fetchTrainStops gets the stops for one train marking those between station_code
+// and dest_code as on route
+func fetchTrainStops(timetable_url string, station_code string, dest_code string) ([]TrainStop, error) {
+	service, err := fetchTrainService(timetable_url, station_code, dest_code)
+	return service.Stops, err
+}
+
+// fetchTrainService is fetchTrainStops keeping the rest of the timetable, such as
+// the date the service runs on
+func fetchTrainService(timetable_url string, station_code string, dest_code string) (TrainStops, error) {
 	resp, err := grequests.Get(timetable_url, nil)
 	if err != nil {
-		log.Fatalln("Unable to make stops request: ", err)
+		return TrainStops{}, fmt.Errorf("Unable to make stops request: %v", err)
 	}
 	respStr := resp.String()
-	stops := &TrainStops{}
-	if err := resp.JSON(stops); err != nil {
-		log.Fatal("Cannot serialize JSON: ", err)
+	debugResponse(resp, respStr)
+	if !resp.Ok {
+		return TrainStops{}, fmt.Errorf("Stops request failed with status %d", resp.StatusCode)
 	}
+	service := TrainStops{}
+	if err := resp.JSON(&service); err != nil {
+		return TrainStops{}, fmt.Errorf("Cannot serialize JSON: %v", err)
+	}
+	slog.Debug("Stops", "stops", fmt.Sprintf("%+v", service))
+	service.Stops = markOnRoute(service.Stops, station_code, dest_code)
+	return service, nil
+}
+
+// markOnRoute marks the stops from station_code to dest_code as on route
+func markOnRoute(stops []TrainStop, station_code string, dest_code string) []TrainStop {
+	var arr []TrainStop
 	on_route := false
-	for i := 0; i < len(stops.Stops); i++ {
-		stop := stops.Stops[i]
+	for i := 0; i < len(stops); i++ {
+		stop := stops[i]
 		stationCode := stop.StationCode
 		if stationCode == station_code {
 			stop.OnRoute = true
@@ -273,12 +359,7 @@ func StopProducer(timetable_url string, station_code string, dest_code string, v
 		}
 		arr = append(arr, stop)
 	}
-	if verbose {
-		fmt.Println(fmt.Sprintf("Base URL: %s", timetable_url))
-		fmt.Println(fmt.Sprintf("Response:\n%s", respStr))
-		fmt.Println(fmt.Sprintf("Stops:\n%+v", stops))
-	}
-	ch <- arr
+	return arr
 }
 
 func StopConsumer(ch <-chan []TrainStop) []TrainStop {
@@ -287,65 +368,90 @@ func StopConsumer(ch <-chan []TrainStop) []TrainStop {
 
 func formatHeader(d TrainJourney) string {
 	header := fmt.Sprintf("==== Trains from %s (%s) to %s", d.StationName, d.StationCode, d.DestinationName)
-	header += fmt.Sprintf("(%s) %s %s ====", d.DestinationCode, d.TimeOfDay, d.Date)
+	header += fmt.Sprintf("(%s) %s %s", d.DestinationCode, d.TimeOfDay, d.Date)
+	if d.TimetableOnly {
+		header += " " + TIMETABLE_ONLY
+	}
+	header += " ===="
 	return header
 }
 
-func formatDeparture(train TrainDeparture, stops []TrainStop, journey TrainJourney) string {
+func formatDeparture(trip TrainTrip, journey TrainJourney) string {
 	var source TrainStop
 	var dest TrainStop
-	var route []TrainStop
-	for i := 0; i < len(stops); i++ {
-		stop := stops[i]
+	for i := 0; i < len(trip.Stops); i++ {
+		stop := trip.Stops[i]
 		if stop.StationCode == journey.StationCode {
 			source = stop
 		}
 		if stop.StationCode == journey.DestinationCode {
 			dest = stop
 		}
-		if stop.OnRoute {
-			route = append(route, stop)
-		}
 	}
-	deptime := train.ExpectedDeparture
-	if len(deptime) == 0 {
-		deptime = train.AimedDeparture
-	}
-	departure := fmt.Sprintf("%s %s -> %s", journey.StationCode, deptime, journey.DestinationCode)
-	departure += fmt.Sprintf(" %s => %s\n", train.ExpectedArrival, train.Status)
-	departure += fmt.Sprintf("\tTrain %s (%s) from %s", train.TrainUid, train.Operator, train.OriginName)
-	departure += fmt.Sprintf(" arriving at %s on platform %s", journey.StationName, source.Platform)
-	departure += fmt.Sprintf(" going to %s platform %s.  %d stops:", journey.DestinationName, dest.Platform, len(route))
+	departure := fmt.Sprintf("%s %s -> %s", journey.StationCode, trip.OriginDeparture, journey.DestinationCode)
+	departure += fmt.Sprintf(" %s => %s\n", trip.DestinationArrival, trip.Status)
+	departure += fmt.Sprintf("\tTrain %s (%s) from %s", trip.TrainUid, trip.Operator, trip.OriginName)
+	departure += fmt.Sprintf(" arriving at %s on %s", journey.StationName, formatPlatform(source))
+	departure += fmt.Sprintf(" going to %s %s.", journey.DestinationName, formatPlatform(dest))
+	departure += fmt.Sprintf("  %d mins, %d intermediate stops:", trip.DurationMins, trip.IntermediateStops)
 	return departure
 }
 
-func formatTrains(journey TrainJourney, verbose bool) {
+func newTrainBoard(journey TrainJourney, trips []TrainTrip) TrainBoard {
+	return TrainBoard{
+		Date:            journey.Date,
+		TimeOfDay:       journey.TimeOfDay,
+		StationName:     journey.StationName,
+		StationCode:     journey.StationCode,
+		DestinationName: journey.DestinationName,
+		DestinationCode: journey.DestinationCode,
+		TimetableOnly:   journey.TimetableOnly,
+		Trains:          trips,
+	}
+}
+
+func formatTrains(journey TrainJourney, trips []TrainTrip, format string) {
 	// Keys: "date", "time_of_day", "request_time", "station_name", "station_code", "departures"
 	// where "departures" is a dict with one key "all" which is a list of dicts of train departures
-	printHeader(formatHeader(journey))
-	departures := journey.Departures.All
-	if verbose {
-		fmt.Println(fmt.Sprintf("All departures:\n%+v", departures))
-	}
-	ch := make(chan []TrainStop)
-	for _, train := range departures {
-		// We need to make a GET request on the timetable URL to retrieve array of stops.
-		// We are then only interested in a subset of the details fields for each stop.
-		// We also want to add whether that stop is on the designated journey or not.
-		// You can modify the request by passing an optional RequestOptions struct
-		//r = requests.get(timetable_url)
-		go StopProducer(train.ServiceTimetable.Url, journey.StationCode, journey.DestinationCode, verbose, ch)
-		stops := StopConsumer(ch)
-		//stops := getStops(train.ServiceTimetable.Url, journey.StationCode, journey.DestinationCode, verbose)
-		if verbose {
-			fmt.Println(fmt.Sprintf("Train %s stopping point details:\n%+v", train.TrainUid, stops))
+	switch format {
+	case "json":
+		printTrainsJSON(journey, trips)
+	case "table":
+		printHeader(formatHeader(journey))
+		printTrainsTable(journey, trips)
+	default:
+		printHeader(formatHeader(journey))
+		for _, trip := range trips {
+			printTrainDetails(formatDeparture(trip, journey), trip.Stops)
 		}
-		trainDetails := formatDeparture(train, stops, journey)
-		printTrainDetails(trainDetails, stops)
 	}
 	fmt.Println()
 }
 
+func printTrainsJSON(journey TrainJourney, trips []TrainTrip) {
+	data, err := json.MarshalIndent(newTrainBoard(journey, trips), "", "  ")
+	if err != nil {
+		log.Fatal("Cannot serialize JSON: ", err)
+	}
+	fmt.Print(string(data))
+}
+
+func printTrainsTable(journey TrainJourney, trips []TrainTrip) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPART\tARRIVE\tMINS\tSTOPS\tPLAT\tSTATUS\tTRAIN\tOPERATOR\tFROM")
+	for _, trip := range trips {
+		platform := ""
+		for _, stop := range trip.Stops {
+			if stop.StationCode == journey.StationCode {
+				platform = tablePlatform(stop)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", trip.OriginDeparture, trip.DestinationArrival,
+			trip.DurationMins, trip.IntermediateStops, platform, trip.Status, trip.TrainUid, trip.Operator, trip.OriginName)
+	}
+	w.Flush()
+}
+
 func printTrainDetails(trainDetails string, stops []TrainStop) {
 	fmt.Println(trainDetails)
 	printStopNames(stops)
@@ -360,56 +466,219 @@ func printHeader(header string) {
 
 func printStopNames(stops []TrainStop) {
 	stopsOnRoute := ""
+	passesOnRoute := ""
 	for i := 0; i < len(stops); i++ {
 		stop := stops[i]
-		if stop.OnRoute {
+		if !stop.OnRoute {
+			continue
+		}
+		if stop.IsPass() {
+			passesOnRoute += fmt.Sprintf("%s, ", stop.StationName)
+		} else if delay, ok := stop.DelayMins(); ok && delay != 0 {
+			stopsOnRoute += fmt.Sprintf("%s (%+d), ", stop.StationName, delay)
+		} else {
 			stopsOnRoute += fmt.Sprintf("%s, ", stop.StationName)
 		}
 	}
 	fmt.Println(fmt.Sprintf("\t%s", stopsOnRoute[:len(stopsOnRoute)-2]))
+	if len(passesOnRoute) > 0 {
+		fmt.Println(fmt.Sprintf("\tpasses through %s", passesOnRoute[:len(passesOnRoute)-2]))
+	}
 }
 
 // ---------- main  ----------
 func procOpts(opts *docopt.Opts) {
 	var conf struct {
-		StationCode     string `docopt:"<from>"`
-		DestinationCode string `docopt:"<to>"`
+		Service         bool     `docopt:"service"`
+		RoundTrip       bool     `docopt:"roundtrip"`
+		Back            string   `docopt:"--back"`
+		Plan            bool     `docopt:"plan"`
+		Via             string   `docopt:"--via"`
+		MinChange       int      `docopt:"--min-change"`
+		TrainUid        string   `docopt:"<train_uid>"`
+		Date            string   `docopt:"--date"`
+		StationCode     string   `docopt:"<from>"`
+		DestinationCode string   `docopt:"<to>"`
+		Fastest         bool     `docopt:"--fastest"`
+		Format          string   `docopt:"--format"`
+		Verbose         bool     `docopt:"--verbose"`
+		LogJSON         bool     `docopt:"--log-json"`
+		Record          bool     `docopt:"record"`
+		Routes          []string `docopt:"<route>"`
+		RoutesFile      string   `docopt:"--routes"`
+		Interval        int      `docopt:"--interval"`
+		DB              string   `docopt:"--db"`
+		PlatformsDB     string   `docopt:"--platforms"`
+		Once            bool     `docopt:"--once"`
+		Stats           bool     `docopt:"stats"`
+		Days            int      `docopt:"--days"`
+		Repay           bool     `docopt:"repay"`
+		Journeys        string   `docopt:"<journeys>"`
+		Schemes         string   `docopt:"--schemes"`
+		Offline         bool     `docopt:"--offline"`
+		Timetable       string   `docopt:"--timetable"`
+		Export          bool     `docopt:"export"`
+		Gtfs            bool     `docopt:"gtfs"`
+		Out             string   `docopt:"--out"`
+	}
+	if err := opts.Bind(&conf); err != nil {
+		log.Fatal("Invalid arguments: ", err)
+	}
+	if conf.Record && conf.Interval < 1 {
+		log.Fatal("--interval must be at least 1 minute")
+	}
+	if err := checkFormat(conf.Format, conf.Stats || conf.Repay); err != nil {
+		log.Fatal(err)
 	}
-	opts.Bind(&conf)
 
 	stationCode := conf.StationCode
 	destCode := conf.DestinationCode
-	verbose := false
+	setupLogging(conf.Verbose, conf.LogJSON)
+	// stats and --offline don't call transportAPI so they work without credentials
+	if !conf.Stats && !conf.Offline {
+		APP_ID = readCred(".transportAppId")
+		APP_KEY = readCred(".transportAppKey")
+	}
 
-	if len(stationCode) == 3 && len(destCode) == 3 {
+	if conf.Record {
+		routes := readRoutes(conf.Routes, conf.RoutesFile)
+		recordRoutes(routes, conf.DB, conf.PlatformsDB, time.Duration(conf.Interval)*time.Minute, conf.Once)
+	} else if conf.Export && conf.Gtfs {
+		routes := readRoutes(conf.Routes, conf.RoutesFile)
+		exportGTFS(routes, conf.Out)
+	} else if conf.Stats {
 		var stationName, destName = validateInputs(stationCode, destCode)
-		trains := getTrainsCallingAt(stationCode, stationName, destCode, destName, verbose)
-		formatTrains(trains, verbose)
+		report := getPunctualityReport(stationCode, stationName, destCode, destName, conf.Days, conf.DB)
+		formatPunctuality(report, conf.Format)
+	} else if conf.Repay {
+		claims := getRepayClaims(conf.Journeys, conf.Schemes)
+		formatClaims(claims, conf.Format)
+	} else if conf.Service {
+		date := conf.Date
+		if len(date) == 0 {
+			date = time.Now().Format(DATE_FORMAT)
+		}
+		service := getServiceTimetable(conf.TrainUid, date)
+		// The trip shares its stops with service so likely platforms are filled in there too
+		trip := TrainTrip{TrainDeparture: TrainDeparture{TrainUid: service.TrainUid}, Stops: service.Stops}
+		learnPlatforms(conf.PlatformsDB, []TrainTrip{trip}, service.Date)
+		formatService(service, conf.Format)
+	} else if len(stationCode) == 3 && len(destCode) == 3 {
+		var stationName, destName = validateInputs(stationCode, destCode)
+		if conf.Plan {
+			plan := planJourney(stationCode, stationName, destCode, destName, conf.Via, conf.MinChange)
+			formatPlan(plan, conf.Format)
+			return
+		}
+		if conf.Offline {
+			now := time.Now()
+			yesterday, today, tomorrow := loadOfflineTimetables(conf.Timetable, now)
+			trains, trips := getOfflineTrainsCallingAt(yesterday, today, tomorrow, stationCode, stationName, destCode, destName, now.Format("15:04"))
+			if conf.Fastest {
+				sortFastest(trips)
+			}
+			formatTrains(trains, trips, conf.Format)
+			return
+		}
+		trains := getTrainsCallingAt(stationCode, stationName, destCode, destName)
+		trips := getTrainTrips(trains)
+		learnPlatforms(conf.PlatformsDB, trips, trains.Date)
+		if conf.Fastest {
+			sortFastest(trips)
+		}
+		if conf.RoundTrip {
+			back := getReturnJourney(stationCode, stationName, destCode, destName, conf.Back)
+			backTrips := getTrainTrips(back)
+			learnPlatforms(conf.PlatformsDB, backTrips, back.Date)
+			if conf.Fastest {
+				sortFastest(backTrips)
+			}
+			formatRoundTrip(trains, trips, back, backTrips, conf.Format)
+		} else {
+			formatTrains(trains, trips, conf.Format)
+		}
 	} else {
 		fmt.Println("Either source or destination not passed in")
 	}
 }
 
+// checkFormat rejects an output format the command can't write.  Only stats and
+// repay write CSV.
+func checkFormat(format string, csv bool) error {
+	switch format {
+	case "text", "table", "json":
+		return nil
+	case "csv":
+		if csv {
+			return nil
+		}
+		return fmt.Errorf("--format=csv is only supported by stats and repay")
+	}
+	return fmt.Errorf("Unknown --format=%s, use text, table, csv or json", format)
+}
+
 func main() {
 	usage := fmt.Sprintf(`
-    %s
+    %[1]s
     ---------
     Usage:
-    %s <from> <to>
-    %s -h | --help
-    %s -V | --version
+    %[1]s service <train_uid> [--date=<date>] [--format=<fmt>] [--platforms=<file>] [-v] [--log-json]
+    %[1]s roundtrip <from> <to> [--back=<time>] [--fastest] [--format=<fmt>] [--platforms=<file>] [-v] [--log-json]
+    %[1]s plan <from> <to> [--via=<crs>] [--min-change=<mins>] [--format=<fmt>] [-v] [--log-json]
+    %[1]s record [<route>...] [--routes=<file>] [--interval=<mins>] [--db=<file>] [--platforms=<file>] [--once] [-v] [--log-json]
+    %[1]s export gtfs [<route>...] [--routes=<file>] [--out=<file>] [-v] [--log-json]
+    %[1]s stats <from> <to> [--days=<n>] [--db=<file>] [--format=<fmt>] [-v] [--log-json]
+    %[1]s repay <journeys> [--schemes=<file>] [--format=<fmt>] [-v] [--log-json]
+    %[1]s <from> <to> [--fastest] [--format=<fmt>] [--platforms=<file>] [--offline [--timetable=<file>]] [-v] [--log-json]
+    %[1]s -h | --help
+    %[1]s -V | --version
 
     Options:
     -h --help               Show this screen.
     -V --version            Show version.
+    --fastest               Sort trains by journey time rather than departure.
+    --format=<fmt>          Output format: text, table or json, or csv for stats and repay [default: text].
+    --date=<date>           Service date as YYYY-MM-DD, today if not given.
+    --back=<time>           Return board from HH:MM today, live if not given.
+    --via=<crs>             Interchange station, otherwise likely ones are tried.
+    --min-change=<mins>     Minimum connection time in minutes [default: 5].
+    --routes=<file>         File of FROM:TO routes, one per line.
+    --interval=<mins>       Minutes between snapshots [default: 5].
+    --db=<file>             History database [default: %[2]s].
+    --platforms=<file>      Platform history database [default: %[5]s].
+    --once                  Take one snapshot and exit, eg. from cron.
+    --out=<file>            GTFS zip file to write [default: %[4]s].
+    --days=<n>              Days of recorded history to report on [default: 30].
+    --schemes=<file>        CSV of operator,first_band for Delay Repay 30 operators.
+    --offline               Scheduled trains from a local timetable, no live data.
+    --timetable=<file>      Network Rail CIF or JSON timetable [default: %[3]s].
+    -v --verbose            Log transportAPI requests and responses to stderr.
+    --log-json              Log JSON objects rather than text.
 
     Examples
     1. trains from RDG to PAD:
-    %s RDG PAD
-`, PROGRAM, PROGRAM, PROGRAM, PROGRAM, PROGRAM)
-	APP_ID = readCred(".transportAppId")
-	APP_KEY = readCred(".transportAppKey")
-
+    %[1]s RDG PAD
+    2. quickest trains from OXF to PAD as a table:
+    %[1]s OXF PAD --fastest --format=table
+    3. every stop for train C20803 today:
+    %[1]s service C20803
+    4. morning trains from OXF to PAD and evening trains back from 17:30:
+    %[1]s roundtrip OXF PAD --back=17:30
+    5. trains from TWY to OXF changing at RDG with at least 8 minutes to change:
+    %[1]s plan TWY OXF --via=RDG --min-change=8
+    6. trains from RDG to PAD logging each transportAPI call as JSON:
+    %[1]s RDG PAD -v --log-json
+    7. record punctuality of trains between OXF and PAD every 5 minutes:
+    %[1]s record OXF:PAD PAD:OXF
+    8. punctuality of recorded trains from OXF to PAD over the last week as CSV:
+    %[1]s stats OXF PAD --days=7 --format=csv
+    9. Delay Repay claims for the journeys logged in journeys.csv:
+    %[1]s repay journeys.csv --format=csv
+    10. scheduled trains from OXF to PAD from a downloaded timetable when transportAPI is down:
+    %[1]s OXF PAD --offline --timetable=timetable.cif.gz
+    11. GTFS feed of the trains between OXF and PAD:
+    %[1]s export gtfs OXF:PAD PAD:OXF
+`, PROGRAM, HISTORY_DB, TIMETABLE_FILE, GTFS_FILE, PLATFORMS_DB)
 	// Process error handling
 	version := fmt.Sprintf("%s %s %s", VERSION, DATE, AUTHOR)
 	opts, _ := docopt.ParseArgs(usage, os.Args[1:], version)
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		csv     bool
+		wantErr bool
+	}{
+		{"text", false, false},
+		{"table", false, false},
+		{"json", true, false},
+		{"csv", true, false},
+		// board, plan and service can't write CSV
+		{"csv", false, true},
+		{"xml", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if err := checkFormat(tt.format, tt.csv); (err != nil) != tt.wantErr {
+				t.Errorf("checkFormat(%q, %v) = %v, want error %v", tt.format, tt.csv, err, tt.wantErr)
+			}
+		})
+	}
+}